@@ -0,0 +1,314 @@
+package graphql
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/lib/pq"
+)
+
+// apiKeyPrefix starts every API key, so leaked keys are easy to search for.
+const apiKeyPrefix = "gql_"
+
+// apiKeyUseInterval is how stale an API key's last used time can get before
+// it is updated, so busy keys don't write on every request.
+const apiKeyUseInterval = time.Minute
+
+// APIKey is a named key a user can authenticate with in the X-API-AUTH header.
+// Requests made with a key can only use fields with one of its scopes. Only a
+// hash of the key is stored.
+type APIKey struct {
+	ID       string     `json:"id"`
+	UserID   string     `json:"-"`
+	Name     string     `json:"name"`
+	Prefix   string     `json:"prefix"`
+	Scopes   []Scope    `json:"scopes"`
+	Expires  *time.Time `json:"expires"`
+	LastUsed *time.Time `json:"last_used"`
+	Revoked  *time.Time `json:"revoked"`
+	Created  time.Time  `json:"created"`
+	Modified time.Time  `json:"modified"`
+}
+
+// Name returns the name of the scope used in docs, such as "stats:write".
+func (s Scope) Name() string {
+	return strings.Replace(s.String(), "_", ":", 1)
+}
+
+// User returns the user the key authenticates as.
+func (k *APIKey) User(ctx context.Context) (*User, error) {
+	return LoadUser(ctx, k.UserID)
+}
+
+// HasScope reports whether the key can use fields with scope s.
+func (k *APIKey) HasScope(s Scope) bool {
+	for _, scope := range k.Scopes {
+		if scope == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Usable returns an error if the key is revoked or expired.
+func (k *APIKey) Usable(now time.Time) error {
+	if k.Revoked != nil {
+		return fmt.Errorf("api key %q was revoked", k.Prefix)
+	}
+
+	if k.Expires != nil && !now.Before(*k.Expires) {
+		return fmt.Errorf("api key %q expired", k.Prefix)
+	}
+
+	return nil
+}
+
+// HashAPIKey returns the hash of a key that is stored in the database.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// newAPIKeySecret returns a random key and the prefix shown to identify it.
+func newAPIKeySecret() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:len(apiKeyPrefix)+8], nil
+}
+
+// CreateAPIKey makes a new key for a user. The key is returned, and can't be
+// retrieved again.
+func CreateAPIKey(ctx context.Context, u *User, name string, scopes []Scope, expires *time.Time) (*APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", fmt.Errorf("api key name cannot be empty")
+	}
+
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("api key must have at least one scope")
+	}
+
+	strs := make([]string, len(scopes))
+	for i, s := range scopes {
+		if !s.IsValid() {
+			return nil, "", fmt.Errorf("invalid scope %q", s)
+		}
+		strs[i] = s.String()
+	}
+
+	if expires != nil && !expires.After(time.Now()) {
+		return nil, "", fmt.Errorf("api key must expire in the future")
+	}
+
+	secret, prefix, err := newAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	k := &APIKey{
+		UserID:   u.ID,
+		Name:     strings.TrimSpace(name),
+		Prefix:   prefix,
+		Scopes:   scopes,
+		Expires:  expires,
+		Created:  time.Now(),
+		Modified: time.Now(),
+	}
+
+	row := db.QueryRowContext(ctx, `
+INSERT INTO api_keys(user_id, name, hash, prefix, scopes, expires_at, created_at, modified_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id
+`, k.UserID, k.Name, HashAPIKey(secret), k.Prefix, pq.Array(strs), k.Expires, k.Created, k.Modified)
+	if err := row.Scan(&k.ID); err != nil {
+		return nil, "", err
+	}
+
+	return k, secret, nil
+}
+
+// GetAPIKeys returns a user's keys, newest first.
+func GetAPIKeys(ctx context.Context, u *User) ([]*APIKey, error) {
+	return apiKeyQuery(ctx, "SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at, modified_at FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC, id DESC", u.ID)
+}
+
+// GetAPIKey returns a key by ID.
+func GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	keys, err := apiKeyQuery(ctx, "SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at, modified_at FROM api_keys WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no api key %q", id)
+	}
+
+	return keys[0], nil
+}
+
+// RevokeAPIKey stops a key from being used. Users can revoke their own keys,
+// and admins can revoke anyone's.
+func RevokeAPIKey(ctx context.Context, u *User, id string) (*APIKey, error) {
+	k, err := GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if k.UserID != u.ID && Role(u.Role) != RoleAdmin {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	if k.Revoked != nil {
+		return k, nil
+	}
+
+	if _, err := db.ExecContext(ctx, "UPDATE api_keys SET (revoked_at, modified_at) = (NOW(), NOW()) WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+
+	return GetAPIKey(ctx, id)
+}
+
+// GetUserByAPIKey returns the key and its user for a secret sent in a
+// request. It fails if the key is unknown, revoked or expired, or if its user
+// is disabled.
+func GetUserByAPIKey(ctx context.Context, secret string) (*User, *APIKey, error) {
+	keys, err := apiKeyQuery(ctx, "SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at, modified_at FROM api_keys WHERE hash = $1", HashAPIKey(secret))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, nil, fmt.Errorf("unknown api key")
+	}
+
+	k := keys[0]
+	now := time.Now()
+	if err := k.Usable(now); err != nil {
+		return nil, nil, err
+	}
+
+	if k.LastUsed == nil || now.Sub(*k.LastUsed) > apiKeyUseInterval {
+		if _, err := db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = $2 WHERE id = $1", k.ID, now); err != nil {
+			return nil, nil, err
+		}
+		k.LastUsed = &now
+	}
+
+	u, err := FindUser(ctx, k.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := u.CheckEnabled(); err != nil {
+		return nil, nil, err
+	}
+
+	return u, k, nil
+}
+
+func apiKeyQuery(ctx context.Context, query string, args ...interface{}) ([]*APIKey, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*APIKey, 0)
+	for rows.Next() {
+		k := new(APIKey)
+		var scopes []string
+		var expires, lastUsed, revoked sql.NullTime
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, pq.Array(&scopes), &expires, &lastUsed, &revoked, &k.Created, &k.Modified); err != nil {
+			return nil, err
+		}
+
+		for _, s := range scopes {
+			k.Scopes = append(k.Scopes, Scope(s))
+		}
+
+		if expires.Valid {
+			k.Expires = &expires.Time
+		}
+
+		if lastUsed.Valid {
+			k.LastUsed = &lastUsed.Time
+		}
+
+		if revoked.Valid {
+			k.Revoked = &revoked.Time
+		}
+
+		keys = append(keys, k)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// WithAPIKey puts the API key a request was made with in the context.
+func WithAPIKey(ctx context.Context, k *APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyCtxKey, k)
+}
+
+// GetAPIKeyFromContext returns the API key a request was made with, or nil if
+// it wasn't made with one.
+func GetAPIKeyFromContext(ctx context.Context) *APIKey {
+	k, ok := ctx.Value(apiKeyCtxKey).(*APIKey)
+	if !ok {
+		return nil
+	}
+
+	return k
+}
+
+// ScopeError is returned when an API key isn't allowed to do something. Its
+// message says why, so it is shown to clients unchanged.
+type ScopeError struct {
+	msg string
+}
+
+func (e *ScopeError) Error() string {
+	return e.msg
+}
+
+// CheckScope returns an error if the request was made with an API key that
+// doesn't have scope s. Requests made without an API key always pass.
+func CheckScope(ctx context.Context, s Scope) error {
+	if k := GetAPIKeyFromContext(ctx); k != nil && !k.HasScope(s) {
+		return &ScopeError{msg: fmt.Sprintf("forbidden: api key is missing scope %s", s.Name())}
+	}
+
+	return nil
+}
+
+// checkFieldScoped returns an error if the request was made with an API key
+// and the field being resolved has no @hasScope directive. This stops keys
+// from reaching fields that were never given a scope.
+func checkFieldScoped(ctx context.Context) error {
+	if GetAPIKeyFromContext(ctx) == nil {
+		return nil
+	}
+
+	fc := graphql.GetFieldContext(ctx)
+	if fc != nil && fc.Field.Definition != nil && fc.Field.Definition.Directives.ForName("hasScope") != nil {
+		return nil
+	}
+
+	return &ScopeError{msg: "forbidden: api keys cannot be used for this field"}
+}
@@ -0,0 +1,45 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.41
+
+import (
+	"context"
+	"fmt"
+)
+
+// CreateAPIKey is the resolver for the createAPIKey field.
+func (r *mutationResolver) CreateAPIKey(ctx context.Context, input NewAPIKey) (*CreatedAPIKey, error) {
+	u := GetUserFromContext(ctx)
+	if u == nil {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	k, secret, err := CreateAPIKey(ctx, u, input.Name, input.Scopes, input.Expires)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedAPIKey{Key: secret, APIKey: k}, nil
+}
+
+// RevokeAPIKey is the resolver for the revokeAPIKey field.
+func (r *mutationResolver) RevokeAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	u := GetUserFromContext(ctx)
+	if u == nil {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	return RevokeAPIKey(ctx, u, id)
+}
+
+// APIKeys is the resolver for the apiKeys field.
+func (r *queryResolver) APIKeys(ctx context.Context) ([]*APIKey, error) {
+	u := GetUserFromContext(ctx)
+	if u == nil {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	return GetAPIKeys(ctx, u)
+}
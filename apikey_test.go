@@ -0,0 +1,123 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func TestScopeName(t *testing.T) {
+	if got := ScopeStatsWrite.Name(); got != "stats:write" {
+		t.Errorf("Name() = %q, want stats:write", got)
+	}
+}
+
+func TestAPIKeyUsable(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := map[string]struct {
+		key *APIKey
+		ok  bool
+	}{
+		"forever":   {key: &APIKey{}, ok: true},
+		"unexpired": {key: &APIKey{Expires: &future}, ok: true},
+		"expired":   {key: &APIKey{Expires: &past}},
+		"expiring":  {key: &APIKey{Expires: &now}},
+		"revoked":   {key: &APIKey{Revoked: &past, Expires: &future}},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if err := tc.key.Usable(now); (err == nil) != tc.ok {
+				t.Errorf("Usable() = %v, want ok %t", err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestNewAPIKeySecret(t *testing.T) {
+	key, prefix, err := newAPIKeySecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(key, apiKeyPrefix) || !strings.HasPrefix(key, prefix) || len(prefix) >= len(key) {
+		t.Errorf("newAPIKeySecret() = %q, %q", key, prefix)
+	}
+
+	other, _, err := newAPIKeySecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key == other || HashAPIKey(key) == HashAPIKey(other) {
+		t.Error("newAPIKeySecret() returned the same key twice")
+	}
+}
+
+func fieldContext(ctx context.Context, directives ...string) context.Context {
+	def := &ast.FieldDefinition{Name: "field"}
+	for _, d := range directives {
+		def.Directives = append(def.Directives, &ast.Directive{Name: d})
+	}
+
+	return graphql.WithFieldContext(ctx, &graphql.FieldContext{
+		Field: graphql.CollectedField{Field: &ast.Field{Name: "field", Definition: def}},
+	})
+}
+
+func TestScopeChecks(t *testing.T) {
+	key := &APIKey{Scopes: []Scope{ScopeStatsWrite}}
+
+	tests := map[string]struct {
+		ctx      context.Context
+		scope    Scope
+		scopeOK  bool
+		scopedOK bool
+	}{
+		"no key": {
+			ctx:      fieldContext(context.Background()),
+			scope:    ScopePostsWrite,
+			scopeOK:  true,
+			scopedOK: true,
+		},
+		"key with scope": {
+			ctx:      fieldContext(WithAPIKey(context.Background(), key), "hasRole", "hasScope"),
+			scope:    ScopeStatsWrite,
+			scopeOK:  true,
+			scopedOK: true,
+		},
+		"key without scope": {
+			ctx:      fieldContext(WithAPIKey(context.Background(), key), "hasRole", "hasScope"),
+			scope:    ScopePostsWrite,
+			scopedOK: true,
+		},
+		"key on unscoped field": {
+			ctx:     fieldContext(WithAPIKey(context.Background(), key), "hasRole"),
+			scope:   ScopeStatsWrite,
+			scopeOK: true,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if err := CheckScope(tc.ctx, tc.scope); (err == nil) != tc.scopeOK {
+				t.Errorf("CheckScope() = %v, want ok %t", err, tc.scopeOK)
+			}
+
+			if err := checkFieldScoped(tc.ctx); (err == nil) != tc.scopedOK {
+				t.Errorf("checkFieldScoped() = %v, want ok %t", err, tc.scopedOK)
+			}
+		})
+	}
+}
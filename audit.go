@@ -0,0 +1,229 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.uber.org/zap"
+)
+
+// redactedArgs are argument names, and fields of input objects, whose values
+// are never written to the audit log. Names are matched case insensitively,
+// ignoring underscores.
+var redactedArgs = map[string]bool{
+	"secret":        true,
+	"password":      true,
+	"token":         true,
+	"apikey":        true,
+	"authorization": true,
+}
+
+// redacted replaces the values of redacted arguments.
+const redacted = "[REDACTED]"
+
+// AuditEntry is a record of a mutation that needed a signed in user.
+type AuditEntry struct {
+	ID            string      `json:"id"`
+	UserID        string      `json:"user_id"`
+	AuthMethod    AuthMethod  `json:"auth_method"`
+	Operation     string      `json:"operation"`
+	OperationName string      `json:"operation_name"`
+	Variables     string      `json:"variables"`
+	Status        AuditStatus `json:"status"`
+	Error         *string     `json:"error"`
+	Created       time.Time   `json:"created"`
+}
+
+// User returns the user who ran the mutation. It is nil if nobody was signed
+// in, or if the user has since been deleted.
+func (e *AuditEntry) User(ctx context.Context) (*User, error) {
+	if e.UserID == "" {
+		return nil, nil
+	}
+
+	u, err := FindUser(ctx, e.UserID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return u, err
+}
+
+// AuditLog is a gqlgen extension that records every mutation guarded by
+// @hasRole or @loggedIn, including ones that were forbidden, to the
+// audit_log table.
+type AuditLog struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = AuditLog{}
+
+// ExtensionName returns the extension name.
+func (AuditLog) ExtensionName() string {
+	return "AuditLog"
+}
+
+// Validate is required for graphql.HandlerExtension.
+func (AuditLog) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptField resolves the field, then records it if it is an audited
+// mutation. Failing to record an entry doesn't fail the mutation.
+func (AuditLog) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	res, err := next(ctx)
+
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Object != "Mutation" || !audited(fc) {
+		return res, err
+	}
+
+	var opName string
+	var vars map[string]interface{}
+	if graphql.HasOperationContext(ctx) {
+		oc := graphql.GetOperationContext(ctx)
+		opName = oc.OperationName
+		vars = oc.Variables
+	}
+
+	e := &AuditEntry{
+		AuthMethod:    authMethod(ctx),
+		Operation:     fc.Field.Name,
+		OperationName: opName,
+		Status:        AuditStatusSuccess,
+	}
+
+	if u := GetUserFromContext(ctx); u != nil {
+		e.UserID = u.ID
+	}
+
+	if err != nil {
+		msg := err.Error()
+		e.Error = &msg
+		e.Status = AuditStatusFailed
+		if strings.HasPrefix(msg, "forbidden") {
+			e.Status = AuditStatusForbidden
+		}
+	}
+
+	e.Variables = "{}"
+	if args, jerr := json.Marshal(redactArgs(fc.Field.ArgumentMap(vars))); jerr != nil {
+		log.Errorw("could not encode audit variables", "operation", e.Operation, zap.Error(jerr))
+	} else {
+		e.Variables = string(args)
+	}
+
+	// Record the entry even if the client has gone away.
+	if serr := e.Save(context.WithoutCancel(ctx)); serr != nil {
+		log.Errorw("could not save audit entry", "operation", e.Operation, "user", e.UserID, zap.Error(serr))
+	}
+
+	return res, err
+}
+
+// audited reports whether the field has a directive that needs a signed in
+// user.
+func audited(fc *graphql.FieldContext) bool {
+	def := fc.Field.Definition
+	if def == nil {
+		return false
+	}
+
+	return def.Directives.ForName("hasRole") != nil || def.Directives.ForName("loggedIn") != nil
+}
+
+// authMethod returns how the request was authenticated.
+func authMethod(ctx context.Context) AuthMethod {
+	switch {
+	case GetAPIKeyFromContext(ctx) != nil:
+		return AuthMethodAPIKey
+	case GetUserFromContext(ctx) != nil:
+		return AuthMethodJwt
+	default:
+		return AuthMethodNone
+	}
+}
+
+// redactArgs returns a copy of args with the values of redacted arguments
+// replaced, at any depth.
+func redactArgs(args map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if redactedArgs[strings.ReplaceAll(strings.ToLower(k), "_", "")] {
+			out[k] = redacted
+			continue
+		}
+		out[k] = redactValue(v)
+	}
+
+	return out
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return redactArgs(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = redactValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Save appends the entry to the audit log. Entries can never be changed.
+func (e *AuditEntry) Save(ctx context.Context) error {
+	if e.Created.IsZero() {
+		e.Created = time.Now()
+	}
+
+	row := db.QueryRowContext(ctx, `
+INSERT INTO audit_log(user_id, auth_method, operation, operation_name, variables, status, error, created_at)
+VALUES (NULLIF($1, ''), $2, $3, $4, $5, $6, $7, $8)
+RETURNING id
+`, e.UserID, e.AuthMethod, e.Operation, e.OperationName, e.Variables, e.Status, e.Error, e.Created)
+
+	return row.Scan(&e.ID)
+}
+
+// GetAuditLog returns audit entries, newest first. Every filter is optional.
+// from is inclusive and to is exclusive.
+func GetAuditLog(ctx context.Context, userID, operation *string, from, to *time.Time, limit, offset int) ([]*AuditEntry, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT id, COALESCE(user_id, ''), auth_method, operation, operation_name, variables, status, error, created_at
+FROM audit_log
+WHERE ($1::text IS NULL OR user_id = $1)
+  AND ($2::text IS NULL OR operation = $2)
+  AND ($3::timestamptz IS NULL OR created_at >= $3)
+  AND ($4::timestamptz IS NULL OR created_at < $4)
+ORDER BY created_at DESC, id DESC
+LIMIT $5 OFFSET $6
+`, userID, operation, from, to, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*AuditEntry, 0)
+	for rows.Next() {
+		e := new(AuditEntry)
+		if err := rows.Scan(&e.ID, &e.UserID, &e.AuthMethod, &e.Operation, &e.OperationName, &e.Variables, &e.Status, &e.Error, &e.Created); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,17 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.41
+
+import (
+	"context"
+	"time"
+)
+
+// AuditLog is the resolver for the auditLog field.
+func (r *queryResolver) AuditLog(ctx context.Context, userID *string, operation *string, from *time.Time, to *time.Time, input *Limit) ([]*AuditEntry, error) {
+	limit, offset := ParseLimit(input, 50, 0)
+
+	return GetAuditLog(ctx, userID, operation, from, to, limit, offset)
+}
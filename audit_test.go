@@ -0,0 +1,80 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+func TestRedactArgs(t *testing.T) {
+	args := map[string]interface{}{
+		"id": "1",
+		"input": map[string]interface{}{
+			"url":    "https://example.com",
+			"secret": "shh",
+			"events": []interface{}{"post_published"},
+		},
+		"api_key": "gql_abc",
+		"list":    []interface{}{map[string]interface{}{"Token": "abc", "key": "visitors"}},
+	}
+
+	got, err := json.Marshal(redactArgs(args))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"api_key":"[REDACTED]","id":"1","input":{"events":["post_published"],"secret":"[REDACTED]","url":"https://example.com"},"list":[{"Token":"[REDACTED]","key":"visitors"}]}`
+	if string(got) != want {
+		t.Errorf("redactArgs() = %s, want %s", got, want)
+	}
+
+	if args["input"].(map[string]interface{})["secret"] != "shh" {
+		t.Error("redactArgs() changed its input")
+	}
+}
+
+func TestAuthMethod(t *testing.T) {
+	u := &User{ID: "user"}
+	tests := map[string]struct {
+		ctx  context.Context
+		want AuthMethod
+	}{
+		"none":    {ctx: context.Background(), want: AuthMethodNone},
+		"jwt":     {ctx: WithUser(context.Background(), u), want: AuthMethodJwt},
+		"api key": {ctx: WithAPIKey(WithUser(context.Background(), u), &APIKey{}), want: AuthMethodAPIKey},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := authMethod(tc.ctx); got != tc.want {
+				t.Errorf("authMethod() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAudited(t *testing.T) {
+	tests := map[string]struct {
+		directives []string
+		want       bool
+	}{
+		"public":    {want: false},
+		"has role":  {directives: []string{"hasRole", "hasScope"}, want: true},
+		"logged in": {directives: []string{"loggedIn"}, want: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			fc := graphql.GetFieldContext(fieldContext(context.Background(), tc.directives...))
+			if got := audited(fc); got != tc.want {
+				t.Errorf("audited() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
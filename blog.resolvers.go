@@ -13,13 +13,32 @@ import (
 func (r *mutationResolver) AddComment(ctx context.Context, input AddComment) (*Comment, error) {
 	c := &Comment{}
 	c.Content = input.Content
-	c.User = GetUserFromContext(ctx)
+	u := GetUserFromContext(ctx)
+	if u != nil {
+		c.UserID = u.ID
+	}
+
+	status, err := NewCommentStatus(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	c.Status = status
 
 	post, err := GetPostString(ctx, input.PostID)
 	if err != nil {
 		return nil, err
 	}
-	c.Post = post
+
+	if post == nil {
+		return nil, fmt.Errorf("no post with id %s", input.PostID)
+	}
+	c.PostID = post.ID
+
+	if input.ParentID != nil && *input.ParentID != "" {
+		if err := c.SetParent(ctx, *input.ParentID); err != nil {
+			return nil, err
+		}
+	}
 
 	err = c.Save(ctx)
 	if err != nil {
@@ -29,6 +48,31 @@ func (r *mutationResolver) AddComment(ctx context.Context, input AddComment) (*C
 	return GetComment(ctx, c.ID)
 }
 
+// EditComment is the resolver for the editComment field.
+func (r *mutationResolver) EditComment(ctx context.Context, id string, content string) (*Comment, error) {
+	return EditComment(ctx, id, content)
+}
+
+// DeleteComment is the resolver for the deleteComment field.
+func (r *mutationResolver) DeleteComment(ctx context.Context, id string) (*Comment, error) {
+	return DeleteComment(ctx, id)
+}
+
+// ApproveComment is the resolver for the approveComment field.
+func (r *mutationResolver) ApproveComment(ctx context.Context, id string) (*Comment, error) {
+	return ModerateComment(ctx, id, CommentStatusApproved)
+}
+
+// RejectComment is the resolver for the rejectComment field.
+func (r *mutationResolver) RejectComment(ctx context.Context, id string) (*Comment, error) {
+	return ModerateComment(ctx, id, CommentStatusRejected)
+}
+
+// MarkCommentSpam is the resolver for the markCommentSpam field.
+func (r *mutationResolver) MarkCommentSpam(ctx context.Context, id string) (*Comment, error) {
+	return ModerateComment(ctx, id, CommentStatusSpam)
+}
+
 // CreatePost is the resolver for the createPost field.
 func (r *mutationResolver) CreatePost(ctx context.Context, input EditPost) (*Post, error) {
 	return r.EditPost(ctx, input)
@@ -63,6 +107,10 @@ func (r *mutationResolver) EditPost(ctx context.Context, input EditPost) (*Post,
 		p.Datetime = *input.Datetime
 	}
 
+	if input.Slug != nil {
+		p.Slug = *input.Slug
+	}
+
 	if input.Draft != nil {
 		p.Draft = *input.Draft
 	} else {
@@ -77,6 +125,26 @@ func (r *mutationResolver) EditPost(ctx context.Context, input EditPost) (*Post,
 	return GetPostString(ctx, p.ID)
 }
 
+// RestorePostRevision is the resolver for the restorePostRevision field.
+func (r *mutationResolver) RestorePostRevision(ctx context.Context, id string) (*Post, error) {
+	return RestorePostRevision(ctx, id)
+}
+
+// EditTag is the resolver for the editTag field.
+func (r *mutationResolver) EditTag(ctx context.Context, input EditTag) (*Tag, error) {
+	return UpdateTag(ctx, input.Name, input.Description, input.Aliases)
+}
+
+// RenameTag is the resolver for the renameTag field.
+func (r *mutationResolver) RenameTag(ctx context.Context, from string, to string) (*Tag, error) {
+	return RenameTag(ctx, from, to)
+}
+
+// MergeTags is the resolver for the mergeTags field.
+func (r *mutationResolver) MergeTags(ctx context.Context, from []string, into string) (*Tag, error) {
+	return MergeTags(ctx, from, into)
+}
+
 // Drafts is the resolver for the drafts field.
 func (r *queryResolver) Drafts(ctx context.Context, input *Limit) ([]*Post, error) {
 	limit, offset := ParseLimit(input, 10, 0)
@@ -91,6 +159,13 @@ func (r *queryResolver) FuturePosts(ctx context.Context, input *Limit) ([]*Post,
 	return FuturePosts(ctx, limit, offset)
 }
 
+// ScheduledPublications is the resolver for the scheduledPublications field.
+func (r *queryResolver) ScheduledPublications(ctx context.Context, input *Limit) ([]*Publication, error) {
+	limit, offset := ParseLimit(input, 10, 0)
+
+	return ScheduledPublications(ctx, limit, offset)
+}
+
 // Posts is the resolver for the posts field.
 func (r *queryResolver) Posts(ctx context.Context, input *Limit) ([]*Post, error) {
 	limit, offset := ParseLimit(input, 10, 0)
@@ -105,6 +180,13 @@ func (r *queryResolver) Comments(ctx context.Context, input *Limit) ([]*Comment,
 	return AllComments(ctx, limit, offset)
 }
 
+// PendingComments is the resolver for the pendingComments field.
+func (r *queryResolver) PendingComments(ctx context.Context, input *Limit) ([]*Comment, error) {
+	limit, offset := ParseLimit(input, 10, 0)
+
+	return PendingComments(ctx, limit, offset)
+}
+
 // Search is the resolver for the search field.
 func (r *queryResolver) Search(ctx context.Context, query string, input *Limit) ([]*Post, error) {
 	limit, offset := ParseLimit(input, 10, 0)
@@ -112,11 +194,23 @@ func (r *queryResolver) Search(ctx context.Context, query string, input *Limit)
 	return Search(ctx, query, limit, offset)
 }
 
+// SearchAll is the resolver for the searchAll field.
+func (r *queryResolver) SearchAll(ctx context.Context, query string, types []SearchType, input *Limit) ([]*SearchResult, error) {
+	limit, offset := ParseLimit(input, 10, 0)
+
+	return SearchAll(ctx, query, types, limit, offset)
+}
+
 // Post is the resolver for the post field.
 func (r *queryResolver) Post(ctx context.Context, id string) (*Post, error) {
 	return GetPostString(ctx, id)
 }
 
+// PostBySlug is the resolver for the postBySlug field.
+func (r *queryResolver) PostBySlug(ctx context.Context, slug string) (*Post, error) {
+	return GetPostBySlug(ctx, slug)
+}
+
 // NextPost is the resolver for the nextPost field.
 func (r *queryResolver) NextPost(ctx context.Context, id string) (*Post, error) {
 	p, err := GetPostString(ctx, id)
@@ -139,10 +233,22 @@ func (r *queryResolver) PrevPost(ctx context.Context, id string) (*Post, error)
 
 // PostsByTag is the resolver for the postsByTag field.
 func (r *queryResolver) PostsByTag(ctx context.Context, id string) ([]*Post, error) {
-	return PostsByTag(ctx, id)
+	return PostsByTag(ctx, id, MaxTagPosts)
 }
 
 // Tags is the resolver for the tags field.
 func (r *queryResolver) Tags(ctx context.Context) ([]string, error) {
 	return AllTags(ctx)
 }
+
+// Tag is the resolver for the tag field.
+func (r *queryResolver) Tag(ctx context.Context, name string) (*Tag, error) {
+	return GetTag(ctx, name)
+}
+
+// AllTags is the resolver for the allTags field.
+func (r *queryResolver) AllTags(ctx context.Context, input *Limit) ([]*Tag, error) {
+	limit, offset := ParseLimit(input, 100, 0)
+
+	return AllTagDetails(ctx, limit, offset)
+}
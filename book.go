@@ -24,6 +24,19 @@ type Book struct {
 // graphql.
 func (Book) IsLinkable() {}
 
+// IsSearchable exists to show that this method implements the Searchable
+// type in graphql.
+func (Book) IsSearchable() {}
+
+// Summary returns the book's title, as that is all we know about it.
+func (b *Book) Summary() string {
+	return b.Title
+}
+
+func (b *Book) GetSummary() string {
+	return b.Summary()
+}
+
 // Save inserts or updates a book into the database.
 func (b *Book) Save(ctx context.Context) error {
 	if b.ID == "" {
@@ -69,6 +82,17 @@ func (b *Book) GetURI() URI {
 	return *b.URI()
 }
 
+// GetBook returns a single book by ID.
+func GetBook(ctx context.Context, id string) (*Book, error) {
+	book := new(Book)
+	row := db.QueryRowContext(ctx, "SELECT id, title, goodreads_id, created_at, modified_at FROM books WHERE id = $1", id)
+	if err := row.Scan(&book.ID, &book.Title, &book.GoodreadsID, &book.Created, &book.Modified); err != nil {
+		return nil, fmt.Errorf("error with get: %w", err)
+	}
+
+	return book, nil
+}
+
 // GetBooks returns all books from the database.
 func GetBooks(ctx context.Context, limit int, offset int) ([]*Book, error) {
 	rows, err := db.QueryContext(ctx, `
@@ -103,3 +127,50 @@ LIMIT $1 OFFSET $2`,
 
 	return books, nil
 }
+
+// GetBooksConnection returns a page of books, ordered by when they were last
+// modified, starting after the cursor.
+func GetBooksConnection(ctx context.Context, first int, after *Cursor) (*BookConnection, error) {
+	t, id := after.args()
+	rows, err := db.QueryContext(ctx, `
+SELECT id, title, goodreads_id, created_at, modified_at
+FROM books
+WHERE ($1::timestamptz IS NULL OR (modified_at, id) > ($1::timestamptz, $2::text))
+ORDER BY modified_at, id
+LIMIT $3`,
+		t, id, first+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conn := &BookConnection{Edges: []*BookEdge{}}
+	var last *Cursor
+	count := 0
+	for rows.Next() {
+		book := new(Book)
+		err := rows.Scan(&book.ID, &book.Title, &book.GoodreadsID, &book.Created, &book.Modified)
+		if err != nil {
+			return nil, err
+		}
+
+		count++
+		if count > first {
+			break
+		}
+
+		last = &Cursor{Time: book.Modified, ID: book.ID}
+		conn.Edges = append(conn.Edges, &BookEdge{Cursor: last.String(), Node: book})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	conn.PageInfo = newPageInfo(count, first, last)
+
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM books").Scan(&conn.TotalCount); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
@@ -2,20 +2,50 @@ package graphql
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// MaxCommentDepth is how deep replies can nest. Top level comments have a
+// depth of zero.
+const MaxCommentDepth = 5
+
+// AutoApproveComments makes new comments from users who have had a comment
+// approved before skip the moderation queue.
+var AutoApproveComments = false
+
 // Comment is a comment on a post.
 type Comment struct {
-	ID       string    `json:"id"`
-	Post     *Post     `json:"post"`
-	User     *User     `json:"author"`
-	Content  string    `json:"content"`
-	Created  time.Time `json:"created"`
-	Modified time.Time `json:"modified"`
+	ID       string        `json:"id"`
+	PostID   string        `json:"post_id"`
+	UserID   string        `json:"user_id"`
+	ParentID string        `json:"parent_id"`
+	Depth    int           `json:"depth"`
+	Content  string        `json:"content"`
+	Status   CommentStatus `json:"status"`
+	Created  time.Time     `json:"created"`
+	Modified time.Time     `json:"modified"`
+	Deleted  *time.Time    `json:"deleted"`
+}
+
+// Post returns the post this comment is on.
+func (c *Comment) Post(ctx context.Context) (*Post, error) {
+	id, err := strconv.ParseInt(c.PostID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid post id %q: %w", c.PostID, err)
+	}
+
+	return LoadPost(ctx, id)
+}
+
+// User returns the author of this comment.
+func (c *Comment) User(ctx context.Context) (*User, error) {
+	return LoadUser(ctx, c.UserID)
 }
 
 // IsLinkable tells gqlgen this model has a URI function.
@@ -30,15 +60,130 @@ func (c *Comment) GetURI() URI {
 	return *c.URI()
 }
 
-// AllComments returns all comments orderd by time.
+// Parent returns the comment this comment is a reply to. Top level comments,
+// and replies to comments that are no longer public, have no parent.
+func (c *Comment) Parent(ctx context.Context) (*Comment, error) {
+	if c.ParentID == "" {
+		return nil, nil
+	}
+
+	p, err := GetComment(ctx, c.ParentID)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case !p.IsApproved():
+		return nil, nil
+	default:
+		return p, nil
+	}
+}
+
+// Replies returns the approved direct replies to this comment, oldest first.
+func (c *Comment) Replies(ctx context.Context, input *Limit) ([]*Comment, error) {
+	limit, offset := ParseLimit(input, 100, 0)
+
+	return commentQuery(
+		ctx, `
+    SELECT id, user_id, post_id, COALESCE(parent_id, ''), depth, content, status, created_at, modified_at, deleted_at
+    FROM comments
+    WHERE parent_id = $1
+      AND status = 'approved'
+    ORDER BY created_at ASC
+    LIMIT $2 OFFSET $3
+    `, c.ID, limit, offset)
+}
+
+// SetParent makes this comment a reply to another comment. The parent must be
+// an approved comment on the same post, and the reply cannot be nested deeper
+// than MaxCommentDepth.
+func (c *Comment) SetParent(ctx context.Context, parentID string) error {
+	p, err := GetComment(ctx, parentID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no comment %q to reply to", parentID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.setParent(p)
+}
+
+func (c *Comment) setParent(p *Comment) error {
+	if !p.IsApproved() {
+		return fmt.Errorf("no comment %q to reply to", p.ID)
+	}
+
+	if p.PostID != c.PostID {
+		return fmt.Errorf("comment %q is on a different post", p.ID)
+	}
+
+	if p.Depth+1 > MaxCommentDepth {
+		return fmt.Errorf("replies can only be nested %d deep", MaxCommentDepth)
+	}
+
+	c.ParentID = p.ID
+	c.Depth = p.Depth + 1
+
+	return nil
+}
+
+// IsApproved returns true if the comment can be shown to the public.
+func (c *Comment) IsApproved() bool {
+	return c.Status == CommentStatusApproved
+}
+
+// AllComments returns all approved comments orderd by time.
 func AllComments(ctx context.Context, limit int, offset int) ([]*Comment, error) {
-	rows, err := db.QueryContext(
+	return commentQuery(
 		ctx, `
-    SELECT id, user_id, post_id, content, created_at, modified_at
+    SELECT id, user_id, post_id, COALESCE(parent_id, ''), depth, content, status, created_at, modified_at, deleted_at
     FROM comments
+    WHERE status = 'approved'
     ORDER BY created_at DESC
     LIMIT $1 OFFSET $2
     `, limit, offset)
+}
+
+// AllCommentsConnection returns a page of approved comments, newest first,
+// starting after the cursor.
+func AllCommentsConnection(ctx context.Context, first int, after *Cursor) (*CommentConnection, error) {
+	t, id := after.args()
+	comments, err := commentQuery(
+		ctx, `
+    SELECT id, user_id, post_id, COALESCE(parent_id, ''), depth, content, status, created_at, modified_at, deleted_at
+    FROM comments
+    WHERE status = 'approved'
+      AND ($1::timestamptz IS NULL OR (created_at, id) < ($1::timestamptz, $2::text))
+    ORDER BY created_at DESC, id DESC
+    LIMIT $3
+    `, t, id, first+1)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &CommentConnection{Edges: []*CommentEdge{}}
+	var last *Cursor
+	for i, c := range comments {
+		if i == first {
+			break
+		}
+
+		last = &Cursor{Time: c.Created, ID: c.ID}
+		conn.Edges = append(conn.Edges, &CommentEdge{Cursor: last.String(), Node: c})
+	}
+	conn.PageInfo = newPageInfo(len(comments), first, last)
+
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments WHERE status = 'approved'").Scan(&conn.TotalCount); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func commentQuery(ctx context.Context, query string, args ...interface{}) ([]*Comment, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -47,31 +192,22 @@ func AllComments(ctx context.Context, limit int, offset int) ([]*Comment, error)
 	comments := make([]*Comment, 0)
 	for rows.Next() {
 		c := &Comment{}
-		var userID string
-		var p int64
-
 		err := rows.Scan(
 			&c.ID,
-			&userID,
-			&p,
+			&c.UserID,
+			&c.PostID,
+			&c.ParentID,
+			&c.Depth,
 			&c.Content,
+			&c.Status,
 			&c.Created,
 			&c.Modified,
+			&c.Deleted,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		c.User, err = GetUser(ctx, userID)
-		if err != nil {
-			return nil, err
-		}
-
-		c.Post, err = GetPost(ctx, p)
-		if err != nil {
-			return nil, err
-		}
-
 		comments = append(comments, c)
 	}
 
@@ -79,7 +215,6 @@ func AllComments(ctx context.Context, limit int, offset int) ([]*Comment, error)
 		return nil, err
 	}
 	return comments, nil
-
 }
 
 // GetComment returns a single comment by ID.
@@ -87,88 +222,111 @@ func GetComment(ctx context.Context, id string) (*Comment, error) {
 	c := &Comment{}
 	row := db.QueryRowContext(
 		ctx, `
-    SELECT id, post_id, user_id, content, created_at, modified_at
+    SELECT id, post_id, user_id, COALESCE(parent_id, ''), depth, content, status, created_at, modified_at, deleted_at
     FROM comments
     WHERE id = $1
     `, id)
 
-	var postID, userID string
 	err := row.Scan(
 		&c.ID,
-		&postID,
-		&userID,
+		&c.PostID,
+		&c.UserID,
+		&c.ParentID,
+		&c.Depth,
 		&c.Content,
+		&c.Status,
 		&c.Created,
 		&c.Modified,
+		&c.Deleted,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	c.User, err = GetUser(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-
-	c.Post, err = GetPostString(ctx, postID)
-	if err != nil {
-		return nil, err
-	}
-
 	return c, nil
 }
 
-// PostComments returns comments for a post ID.
+// PostComments returns approved comments for a post ID.
 func PostComments(ctx context.Context, p string, limit int, offset int) ([]*Comment, error) {
 	if p == "" {
 		return nil, fmt.Errorf("no post specified")
 	}
 
-	rows, err := db.QueryContext(
+	return commentQuery(
 		ctx, `
-    SELECT id, user_id, content, created_at, modified_at
+    SELECT id, user_id, post_id, COALESCE(parent_id, ''), depth, content, status, created_at, modified_at, deleted_at
     FROM comments
     WHERE post_id = $1
+      AND status = 'approved'
     ORDER BY created_at ASC
     LIMIT $2 OFFSET $3
     `, p, limit, offset)
+}
+
+// PostCommentThreads returns approved comments for a post ID ordered by
+// thread. Each top level comment is followed by its replies, depth first, and
+// comments at the same level are oldest first. Replies to comments that are
+// not approved are left out.
+func PostCommentThreads(ctx context.Context, p string, limit int, offset int) ([]*Comment, error) {
+	if p == "" {
+		return nil, fmt.Errorf("no post specified")
+	}
+
+	comments, err := commentQuery(
+		ctx, `
+    SELECT id, user_id, post_id, COALESCE(parent_id, ''), depth, content, status, created_at, modified_at, deleted_at
+    FROM comments
+    WHERE post_id = $1
+      AND status = 'approved'
+    `, p)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	comments := make([]*Comment, 0)
-	for rows.Next() {
-		c := &Comment{}
-		var userID string
-		err := rows.Scan(
-			&c.ID,
-			&userID,
-			&c.Content,
-			&c.Created,
-			&c.Modified,
-		)
-		if err != nil {
-			return nil, err
-		}
+	threads := threadComments(comments)
+	if offset >= len(threads) {
+		return []*Comment{}, nil
+	}
+	threads = threads[offset:]
 
-		c.User, err = GetUser(ctx, userID)
-		if err != nil {
-			return nil, err
-		}
+	if limit >= 0 && limit < len(threads) {
+		threads = threads[:limit]
+	}
 
-		c.Post, err = GetPostString(ctx, p)
-		if err != nil {
-			return nil, err
-		}
+	return threads, nil
+}
 
-		comments = append(comments, c)
+// threadComments orders comments by thread, as described on
+// PostCommentThreads, and sets their depth from where they are in it.
+// Comments whose parent isn't in comments are left out, along with their
+// replies.
+func threadComments(comments []*Comment) []*Comment {
+	replies := map[string][]*Comment{}
+	for _, c := range comments {
+		replies[c.ParentID] = append(replies[c.ParentID], c)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	for _, r := range replies {
+		sort.Slice(r, func(i, j int) bool {
+			if !r[i].Created.Equal(r[j].Created) {
+				return r[i].Created.Before(r[j].Created)
+			}
+			return r[i].ID < r[j].ID
+		})
 	}
-	return comments, nil
+
+	threads := make([]*Comment, 0, len(comments))
+	var walk func(parentID string, depth int)
+	walk = func(parentID string, depth int) {
+		for _, c := range replies[parentID] {
+			c.Depth = depth
+			threads = append(threads, c)
+			walk(c.ID, depth+1)
+		}
+	}
+	walk("", 0)
+
+	return threads
 }
 
 // Save adds the comment to the database and checks that no data is missing.
@@ -187,31 +345,135 @@ func (c *Comment) Save(ctx context.Context) error {
 
 	c.Modified = time.Now()
 
-	if c.Post == nil {
-		return fmt.Errorf("post cannot be nil")
+	if c.PostID == "" {
+		return fmt.Errorf("post cannot be empty")
+	}
+
+	if c.UserID == "" {
+		return fmt.Errorf("user cannot be empty")
+	}
+
+	if c.Status == "" {
+		c.Status = CommentStatusPending
+	}
+
+	if c.Depth > MaxCommentDepth {
+		return fmt.Errorf("replies can only be nested %d deep", MaxCommentDepth)
+	}
+
+	if !c.Status.IsValid() {
+		return fmt.Errorf("invalid comment status %q", c.Status)
 	}
 
-	if c.User == nil {
-		return fmt.Errorf("user cannot be nil")
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var approvedBefore bool
+	row := tx.QueryRowContext(ctx, "SELECT approved_at IS NOT NULL FROM comments WHERE id = $1", c.ID)
+	if err := row.Scan(&approvedBefore); err != nil && err != sql.ErrNoRows {
+		return err
 	}
 
-	if _, err := db.ExecContext(
+	if _, err := tx.ExecContext(
 		ctx,
 		`
-INSERT INTO comments(id, post_id, user_id, content, created_at, modified_at)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO comments(id, post_id, user_id, parent_id, depth, content, status, created_at, modified_at, approved_at)
+VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, CASE WHEN $7 = 'approved' THEN NOW() END)
 ON CONFLICT (id) DO UPDATE
-SET (post_id, user_id, content, created_at, modified_at) = ($2, $3, $4, $5, $6)
+SET (post_id, user_id, parent_id, depth, content, status, created_at, modified_at) = ($2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9),
+  approved_at = COALESCE(comments.approved_at, EXCLUDED.approved_at)
 WHERE comments.id = $1;
 `,
 		c.ID,
-		c.Post.ID,
-		c.User.ID,
+		c.PostID,
+		c.UserID,
+		c.ParentID,
+		c.Depth,
 		c.Content,
+		c.Status,
 		c.Created,
 		c.Modified); err != nil {
 		return err
 	}
 
-	return nil
+	// Comments are added, as far as the public can tell, once they are
+	// first approved. Comments sent back to moderation by an edit have
+	// already been announced, so approving them again doesn't announce them
+	// twice.
+	if !approvedBefore && c.IsApproved() {
+		if err := notify(ctx, tx, CommentAddedChannel, map[string]string{"id": c.ID, "post_id": c.PostID}); err != nil {
+			return err
+		}
+
+		if err := enqueueEvent(ctx, tx, WebhookEventCommentAdded, c); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// NewCommentStatus decides whether a new comment by a user needs moderation.
+// Comments by admins are always approved. If AutoApproveComments is set, so
+// are comments by users who have had a comment approved before.
+func NewCommentStatus(ctx context.Context, u *User) (CommentStatus, error) {
+	if u == nil {
+		return CommentStatusPending, nil
+	}
+
+	if u.Role == string(RoleAdmin) {
+		return CommentStatusApproved, nil
+	}
+
+	if !AutoApproveComments {
+		return CommentStatusPending, nil
+	}
+
+	var approved bool
+	row := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM comments WHERE user_id = $1 AND status = 'approved')", u.ID)
+	if err := row.Scan(&approved); err != nil {
+		return "", fmt.Errorf("check approved comments: %w", err)
+	}
+
+	if approved {
+		return CommentStatusApproved, nil
+	}
+
+	return CommentStatusPending, nil
+}
+
+// PendingComments returns comments waiting for moderation, oldest first.
+func PendingComments(ctx context.Context, limit int, offset int) ([]*Comment, error) {
+	return commentQuery(
+		ctx, `
+    SELECT id, user_id, post_id, COALESCE(parent_id, ''), depth, content, status, created_at, modified_at, deleted_at
+    FROM comments
+    WHERE status = 'pending'
+      AND deleted_at IS NULL
+    ORDER BY created_at ASC
+    LIMIT $1 OFFSET $2
+    `, limit, offset)
+}
+
+// ModerateComment sets the status of a comment. Comments that become approved
+// are sent to commentAdded subscribers and webhooks when saved, as this is
+// when they become public.
+func ModerateComment(ctx context.Context, id string, status CommentStatus) (*Comment, error) {
+	c, err := GetComment(ctx, id)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, fmt.Errorf("no comment %q", id)
+	case err != nil:
+		return nil, err
+	}
+
+	c.Status = status
+	if err := c.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
 }
@@ -0,0 +1,164 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CommentEdit is the content a comment had before it was edited or deleted.
+type CommentEdit struct {
+	ID        string    `json:"id"`
+	CommentID string    `json:"comment_id"`
+	UserID    string    `json:"user_id"`
+	Content   string    `json:"content"`
+	Created   time.Time `json:"created"`
+}
+
+// User returns the user who made the edit.
+func (e *CommentEdit) User(ctx context.Context) (*User, error) {
+	return LoadUser(ctx, e.UserID)
+}
+
+// canChangeComment returns an error unless the user wrote the comment or is
+// an admin.
+func canChangeComment(u *User, c *Comment) error {
+	if u == nil {
+		return fmt.Errorf("must be logged in")
+	}
+
+	if u.Role != string(RoleAdmin) && u.ID != c.UserID {
+		return fmt.Errorf("only the author of a comment can change it")
+	}
+
+	return nil
+}
+
+// History returns the previous versions of a comment, newest first. Only the
+// author of the comment and admins can see them.
+func (c *Comment) History(ctx context.Context) ([]*CommentEdit, error) {
+	if err := canChangeComment(GetUserFromContext(ctx), c); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+SELECT id, comment_id, user_id, content, created_at
+FROM comment_edits
+WHERE comment_id = $1
+ORDER BY id DESC
+`, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := make([]*CommentEdit, 0)
+	for rows.Next() {
+		e := new(CommentEdit)
+		if err := rows.Scan(&e.ID, &e.CommentID, &e.UserID, &e.Content, &e.Created); err != nil {
+			return nil, err
+		}
+		edits = append(edits, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return edits, nil
+}
+
+// EditComment changes the content of a comment, keeping the old content in
+// its history. Edits by users who would not be auto-approved send approved
+// comments back to moderation.
+func EditComment(ctx context.Context, id, content string) (*Comment, error) {
+	u := GetUserFromContext(ctx)
+	c, err := changeableComment(ctx, u, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := c.Status
+	if c.IsApproved() {
+		if status, err = NewCommentStatus(ctx, u); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := changeComment(ctx, u, c, `
+UPDATE comments
+SET (content, status, modified_at) = ($2, $3, NOW())
+WHERE id = $1
+`, c.ID, content, status); err != nil {
+		return nil, fmt.Errorf("edit comment: %w", err)
+	}
+
+	return GetComment(ctx, c.ID)
+}
+
+// DeleteComment removes the content of a comment and marks it as deleted.
+// The comment itself is kept, so replies to it stay in their thread. The old
+// content is kept in its history.
+func DeleteComment(ctx context.Context, id string) (*Comment, error) {
+	u := GetUserFromContext(ctx)
+	c, err := changeableComment(ctx, u, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := changeComment(ctx, u, c, `
+UPDATE comments
+SET (content, modified_at, deleted_at) = ('', NOW(), NOW())
+WHERE id = $1
+`, c.ID); err != nil {
+		return nil, fmt.Errorf("delete comment: %w", err)
+	}
+
+	return GetComment(ctx, c.ID)
+}
+
+// changeableComment gets a comment that has not been deleted and that the
+// user is allowed to change.
+func changeableComment(ctx context.Context, u *User, id string) (*Comment, error) {
+	c, err := GetComment(ctx, id)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, fmt.Errorf("no comment %q", id)
+	case err != nil:
+		return nil, err
+	}
+
+	if err := canChangeComment(u, c); err != nil {
+		return nil, err
+	}
+
+	if c.Deleted != nil {
+		return nil, fmt.Errorf("comment %q has been deleted", id)
+	}
+
+	return c, nil
+}
+
+// changeComment saves the current content of a comment to its history and
+// runs the update in the same transaction.
+func changeComment(ctx context.Context, u *User, c *Comment, update string, args ...interface{}) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO comment_edits(comment_id, user_id, content, created_at)
+VALUES ($1, $2, $3, NOW())
+`, c.ID, u.ID, c.Content); err != nil {
+		return fmt.Errorf("save history: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, update, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,192 @@
+package graphql
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNewCommentStatus(t *testing.T) {
+	tests := map[string]struct {
+		user *User
+		want CommentStatus
+	}{
+		"anonymous": {user: nil, want: CommentStatusPending},
+		"admin":     {user: &User{ID: "a", Role: "admin"}, want: CommentStatusApproved},
+		"normal":    {user: &User{ID: "b", Role: "normal"}, want: CommentStatusPending},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewCommentStatus(context.Background(), tc.user)
+			if err != nil {
+				t.Fatalf("NewCommentStatus: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("NewCommentStatus() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCanChangeComment(t *testing.T) {
+	c := &Comment{ID: "c", UserID: "author"}
+	tests := map[string]struct {
+		user    *User
+		wantErr bool
+	}{
+		"anonymous": {user: nil, wantErr: true},
+		"author":    {user: &User{ID: "author", Role: "normal"}},
+		"admin":     {user: &User{ID: "admin", Role: "admin"}},
+		"stranger":  {user: &User{ID: "someone", Role: "normal"}, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if err := canChangeComment(tc.user, c); (err != nil) != tc.wantErr {
+				t.Errorf("canChangeComment() = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetParent(t *testing.T) {
+	tests := map[string]struct {
+		parent  *Comment
+		wantErr bool
+	}{
+		"top level":    {parent: &Comment{ID: "p", PostID: "1", Status: CommentStatusApproved}},
+		"deepest":      {parent: &Comment{ID: "p", PostID: "1", Status: CommentStatusApproved, Depth: MaxCommentDepth - 1}},
+		"too deep":     {parent: &Comment{ID: "p", PostID: "1", Status: CommentStatusApproved, Depth: MaxCommentDepth}, wantErr: true},
+		"other post":   {parent: &Comment{ID: "p", PostID: "2", Status: CommentStatusApproved}, wantErr: true},
+		"not approved": {parent: &Comment{ID: "p", PostID: "1", Status: CommentStatusPending}, wantErr: true},
+		"rejected":     {parent: &Comment{ID: "p", PostID: "1", Status: CommentStatusRejected}, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c := &Comment{ID: "c", PostID: "1"}
+			err := c.setParent(tc.parent)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("setParent() = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if err == nil && (c.ParentID != tc.parent.ID || c.Depth != tc.parent.Depth+1) {
+				t.Errorf("setParent() left parent %q depth %d, want %q %d", c.ParentID, c.Depth, tc.parent.ID, tc.parent.Depth+1)
+			}
+		})
+	}
+}
+
+func TestThreadComments(t *testing.T) {
+	at := func(min int) time.Time { return time.Date(2023, 10, 1, 12, min, 0, 0, time.UTC) }
+
+	tests := map[string]struct {
+		comments []*Comment
+		want     []string
+		depths   []int
+	}{
+		"parent before children": {
+			comments: []*Comment{
+				{ID: "reply", ParentID: "top", Created: at(1)},
+				{ID: "top", Created: at(0)},
+			},
+			want:   []string{"top", "reply"},
+			depths: []int{0, 1},
+		},
+		"siblings by time": {
+			comments: []*Comment{
+				{ID: "b", Created: at(5)},
+				{ID: "a2", ParentID: "a", Created: at(9)},
+				{ID: "a", Created: at(1)},
+				{ID: "a1", ParentID: "a", Created: at(3)},
+				{ID: "a1x", ParentID: "a1", Created: at(4)},
+			},
+			want:   []string{"a", "a1", "a1x", "a2", "b"},
+			depths: []int{0, 1, 2, 1, 0},
+		},
+		"ties by id": {
+			comments: []*Comment{
+				{ID: "y", Created: at(0)},
+				{ID: "x", Created: at(0)},
+			},
+			want:   []string{"x", "y"},
+			depths: []int{0, 0},
+		},
+		"orphans left out": {
+			comments: []*Comment{
+				{ID: "top", Created: at(0)},
+				{ID: "orphan", ParentID: "pending", Created: at(1)},
+				{ID: "orphan-reply", ParentID: "orphan", Created: at(2)},
+			},
+			want:   []string{"top"},
+			depths: []int{0},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := threadComments(tc.comments)
+			if len(got) != len(tc.want) {
+				t.Fatalf("threadComments() returned %d comments, want %d", len(got), len(tc.want))
+			}
+
+			for i, c := range got {
+				if c.ID != tc.want[i] || c.Depth != tc.depths[i] {
+					t.Errorf("comment %d = %q at depth %d, want %q at depth %d", i, c.ID, c.Depth, tc.want[i], tc.depths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCommentSaveAnnouncesFirstApprovalOnly(t *testing.T) {
+	tests := map[string]struct {
+		approvedBefore bool
+		announced      bool
+	}{
+		"first approval": {approvedBefore: false, announced: true},
+		"approved again": {approvedBefore: true, announced: false},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			m := mockDB(t)
+			c := &Comment{ID: "c", PostID: "1", UserID: "u", Content: "hi", Status: CommentStatusApproved}
+
+			m.ExpectBegin()
+			m.ExpectQuery(regexp.QuoteMeta("SELECT approved_at IS NOT NULL FROM comments WHERE id = $1")).
+				WithArgs("c").
+				WillReturnRows(sqlmock.NewRows([]string{"approved"}).AddRow(tc.approvedBefore))
+			m.ExpectExec(`(?s)INSERT INTO comments.*approved_at = COALESCE\(comments\.approved_at, EXCLUDED\.approved_at\)`).
+				WithArgs("c", "1", "u", "", 0, "hi", CommentStatusApproved, sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			if tc.announced {
+				m.ExpectExec(regexp.QuoteMeta("SELECT pg_notify($1, $2)")).
+					WithArgs(CommentAddedChannel, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectExec(regexp.QuoteMeta("INSERT INTO webhook_deliveries")).
+					WithArgs(WebhookEventCommentAdded.String(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			m.ExpectCommit()
+
+			if err := c.Save(context.Background()); err != nil {
+				t.Errorf("Save() = %v", err)
+			}
+		})
+	}
+}
@@ -330,6 +330,339 @@ var (
 			Description: "Cleanup pages",
 			Script:      `DROP TABLE IF EXISTS pages;`,
 		},
+		{
+			Version:     30,
+			Description: "Add keyset pagination indexes",
+			Script: `
+      CREATE INDEX posts_date_id_idx ON posts (date DESC, id DESC);
+      CREATE INDEX links_created_id_idx ON links (created DESC, id DESC);
+      CREATE INDEX comments_created_at_id_idx ON comments (created_at DESC, id DESC);
+      `,
+		},
+		{
+			Version:     31,
+			Description: "Add post revisions table",
+			Script: `
+      CREATE TABLE post_revisions (
+        id BIGSERIAL PRIMARY KEY,
+        post_id BIGINT NOT NULL,
+        user_id TEXT,
+        title TEXT,
+        content TEXT,
+        created_at TIMESTAMP WITH TIME ZONE
+      );
+      CREATE INDEX post_revisions_post_id_idx ON post_revisions (post_id, id DESC);
+      INSERT INTO post_revisions (post_id, title, content, created_at)
+        SELECT id, title, content, modified_at FROM posts;
+      `,
+		},
+		{
+			Version:     32,
+			Description: "Add slugs to posts",
+			Script: `
+      ALTER TABLE posts ADD COLUMN slug TEXT;
+      UPDATE posts SET slug = TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER(title), '[^a-z0-9]+', '-', 'g'));
+      UPDATE posts SET slug = 'post' WHERE slug = '';
+      UPDATE posts SET slug = 'post-' || slug WHERE slug ~ '^[0-9]+$';
+      UPDATE posts SET slug = slug || '-' || id WHERE slug IN (
+        SELECT slug FROM posts GROUP BY slug HAVING COUNT(*) > 1
+      );
+      ALTER TABLE posts ALTER COLUMN slug SET NOT NULL;
+      CREATE UNIQUE INDEX posts_slug_idx ON posts (slug);
+      CREATE TABLE post_slugs (
+        slug TEXT PRIMARY KEY NOT NULL,
+        post_id BIGINT NOT NULL,
+        created_at TIMESTAMP WITH TIME ZONE
+      );
+      CREATE INDEX post_slugs_post_id_idx ON post_slugs (post_id);
+      `,
+		},
+		{
+			Version:     33,
+			Description: "Add post publications table",
+			Script: `
+      CREATE TABLE post_publications (
+        post_id BIGINT PRIMARY KEY NOT NULL,
+        published_at TIMESTAMP WITH TIME ZONE,
+        processed_at TIMESTAMP WITH TIME ZONE
+      );
+      INSERT INTO post_publications (post_id, published_at, processed_at)
+        SELECT id, date, NOW() FROM posts WHERE draft = false AND date <= NOW();
+      `,
+		},
+		{
+			Version:     34,
+			Description: "Add comment moderation status",
+			Script: `
+      ALTER TABLE comments ADD COLUMN status TEXT NOT NULL DEFAULT 'approved';
+      ALTER TABLE comments ALTER COLUMN status SET DEFAULT 'pending';
+      CREATE INDEX comments_status_created_at_idx ON comments (status, created_at);
+      `,
+		},
+		{
+			Version:     35,
+			Description: "Add comment replies",
+			Script: `
+      ALTER TABLE comments ADD COLUMN parent_id TEXT REFERENCES comments (id) ON DELETE SET NULL;
+      ALTER TABLE comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+      CREATE INDEX comments_parent_id_idx ON comments (parent_id);
+      `,
+		},
+		{
+			Version:     36,
+			Description: "Add comment edit history and soft deletes",
+			Script: `
+      ALTER TABLE comments ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+      CREATE TABLE comment_edits (
+        id SERIAL PRIMARY KEY,
+        comment_id TEXT NOT NULL REFERENCES comments (id) ON DELETE CASCADE,
+        user_id TEXT NOT NULL,
+        content TEXT,
+        created_at TIMESTAMP WITH TIME ZONE
+      );
+      CREATE INDEX comment_edits_comment_id_idx ON comment_edits (comment_id);
+      `,
+		},
+		{
+			Version:     37,
+			Description: "Add stored search vectors",
+			Script: `
+      ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+        setweight(array_to_tsvector(COALESCE(tags, '{}'::text[])), 'A') ||
+        setweight(to_tsvector('english', COALESCE(content, '')), 'B')
+      ) STORED;
+      CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
+
+      ALTER TABLE links ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+        setweight(array_to_tsvector(COALESCE(tags, '{}'::text[])), 'A') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+      ) STORED;
+      CREATE INDEX links_search_vector_idx ON links USING GIN (search_vector);
+
+      ALTER TABLE tweets ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(text, '')), 'A') ||
+        setweight(array_to_tsvector(COALESCE(hashtags, '{}'::text[])), 'A')
+      ) STORED;
+      CREATE INDEX tweets_search_vector_idx ON tweets USING GIN (search_vector);
+
+      ALTER TABLE books ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(title, '')), 'A')
+      ) STORED;
+      CREATE INDEX books_search_vector_idx ON books USING GIN (search_vector);
+      `,
+		},
+		{
+			Version:     38,
+			Description: "Add tags and tag aliases tables",
+			Script: `
+      CREATE TABLE tags (
+        name TEXT PRIMARY KEY NOT NULL,
+        description TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP WITH TIME ZONE,
+        modified_at TIMESTAMP WITH TIME ZONE
+      );
+      CREATE TABLE tag_aliases (
+        alias TEXT PRIMARY KEY NOT NULL,
+        tag TEXT NOT NULL REFERENCES tags (name) ON UPDATE CASCADE ON DELETE CASCADE
+      );
+      CREATE INDEX tag_aliases_tag_idx ON tag_aliases (tag);
+      CREATE INDEX posts_tags_idx ON posts USING GIN (tags);
+      CREATE INDEX links_tags_idx ON links USING GIN (tags);
+      INSERT INTO tags (name, created_at, modified_at) VALUES ('recursecenter', NOW(), NOW());
+      INSERT INTO tag_aliases (alias, tag) VALUES ('hackerschool', 'recursecenter');
+      `,
+		},
+		{
+			Version:     39,
+			Description: "Add post links table",
+			Script: `
+      CREATE TABLE post_links (
+        post_id BIGINT NOT NULL,
+        link_id UUID NOT NULL REFERENCES links (id) ON DELETE CASCADE,
+        position INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (post_id, link_id)
+      );
+      CREATE INDEX post_links_link_id_idx ON post_links (link_id);
+      `,
+		},
+		{
+			Version:     40,
+			Description: "Add webhooks and webhook outbox tables",
+			Script: `
+      CREATE TABLE webhooks (
+        id BIGSERIAL PRIMARY KEY,
+        url TEXT NOT NULL,
+        secret TEXT NOT NULL,
+        events TEXT[] NOT NULL,
+        active BOOLEAN NOT NULL DEFAULT true,
+        created_at TIMESTAMP WITH TIME ZONE,
+        modified_at TIMESTAMP WITH TIME ZONE
+      );
+      CREATE TABLE webhook_deliveries (
+        id BIGSERIAL PRIMARY KEY,
+        webhook_id BIGINT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+        event TEXT NOT NULL,
+        data JSONB NOT NULL,
+        status TEXT NOT NULL DEFAULT 'pending',
+        attempts INTEGER NOT NULL DEFAULT 0,
+        next_attempt_at TIMESTAMP WITH TIME ZONE,
+        created_at TIMESTAMP WITH TIME ZONE,
+        delivered_at TIMESTAMP WITH TIME ZONE
+      );
+      CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+      CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id DESC);
+      CREATE TABLE webhook_attempts (
+        id BIGSERIAL PRIMARY KEY,
+        delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+        attempted_at TIMESTAMP WITH TIME ZONE,
+        status_code INTEGER,
+        error TEXT,
+        duration DOUBLE PRECISION
+      );
+      CREATE INDEX webhook_attempts_delivery_id_idx ON webhook_attempts (delivery_id);
+      `,
+		},
+		{
+			Version:     41,
+			Description: "Add webmentions table",
+			Script: `
+      CREATE TABLE webmentions (
+        id BIGSERIAL PRIMARY KEY,
+        post_id BIGINT NOT NULL,
+        source TEXT NOT NULL,
+        target TEXT NOT NULL,
+        title TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP WITH TIME ZONE,
+        modified_at TIMESTAMP WITH TIME ZONE,
+        UNIQUE (source, target)
+      );
+      CREATE INDEX webmentions_post_id_idx ON webmentions (post_id, created_at);
+      `,
+		},
+		{
+			Version:     42,
+			Description: "Move api keys into a hashed api_keys table",
+			Script: `
+      CREATE TABLE api_keys (
+        id BIGSERIAL PRIMARY KEY,
+        user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        name TEXT NOT NULL,
+        hash TEXT NOT NULL UNIQUE,
+        prefix TEXT NOT NULL,
+        scopes TEXT[] NOT NULL,
+        expires_at TIMESTAMP WITH TIME ZONE,
+        last_used_at TIMESTAMP WITH TIME ZONE,
+        revoked_at TIMESTAMP WITH TIME ZONE,
+        created_at TIMESTAMP WITH TIME ZONE,
+        modified_at TIMESTAMP WITH TIME ZONE
+      );
+      CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
+      INSERT INTO api_keys (user_id, name, hash, prefix, scopes, created_at, modified_at)
+      SELECT id, 'legacy', encode(sha256(convert_to(apikey::text, 'UTF8')), 'hex'), left(apikey::text, 8),
+        ARRAY['posts_read', 'posts_write', 'links_write', 'stats_write', 'books_write', 'tweets_write', 'logs_read', 'logs_write', 'photos_read', 'photos_write'],
+        NOW(), NOW()
+      FROM users WHERE apikey IS NOT NULL;
+      ALTER TABLE users DROP COLUMN apikey;
+      `,
+		},
+		{
+			Version:     43,
+			Description: "Add disabled users",
+			Script: `
+      ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP WITH TIME ZONE;
+      `,
+		},
+		{
+			Version:     44,
+			Description: "Add append only audit log",
+			Script: `
+      CREATE TABLE audit_log (
+        id BIGSERIAL PRIMARY KEY,
+        user_id TEXT,
+        auth_method TEXT NOT NULL,
+        operation TEXT NOT NULL,
+        operation_name TEXT NOT NULL DEFAULT '',
+        variables JSONB NOT NULL,
+        status TEXT NOT NULL,
+        error TEXT,
+        created_at TIMESTAMP WITH TIME ZONE NOT NULL
+      );
+      CREATE INDEX audit_log_created_at_idx ON audit_log (created_at DESC, id DESC);
+      CREATE INDEX audit_log_user_id_idx ON audit_log (user_id, created_at DESC);
+      CREATE INDEX audit_log_operation_idx ON audit_log (operation, created_at DESC);
+      CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+      BEGIN
+        RAISE EXCEPTION 'audit_log is append only';
+      END;
+      $$ LANGUAGE plpgsql;
+      CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+        FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+      CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+        FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+      `,
+		},
+		{
+			Version:     45,
+			Description: "Add stat retention policies and rollups",
+			Script: `
+      CREATE TABLE stat_retention_policies (
+        id BIGSERIAL PRIMARY KEY,
+        pattern TEXT NOT NULL UNIQUE,
+        raw_days INTEGER NOT NULL,
+        hourly_days INTEGER NOT NULL,
+        daily_days INTEGER,
+        created_at TIMESTAMP WITH TIME ZONE,
+        modified_at TIMESTAMP WITH TIME ZONE
+      );
+      CREATE TABLE stats_hourly (
+        key TEXT NOT NULL,
+        bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+        count BIGINT NOT NULL,
+        sum DOUBLE PRECISION NOT NULL,
+        min DOUBLE PRECISION NOT NULL,
+        max DOUBLE PRECISION NOT NULL,
+        last DOUBLE PRECISION NOT NULL,
+        last_at TIMESTAMP WITH TIME ZONE NOT NULL,
+        PRIMARY KEY (key, bucket)
+      );
+      CREATE TABLE stats_daily (LIKE stats_hourly INCLUDING ALL);
+      `,
+		},
+		{
+			Version:     46,
+			Description: "Add stat definitions",
+			Script: `
+      CREATE TABLE stat_definitions (
+        key TEXT PRIMARY KEY,
+        unit TEXT,
+        description TEXT,
+        precision INTEGER,
+        min DOUBLE PRECISION,
+        max DOUBLE PRECISION,
+        higher_is_better BOOLEAN,
+        public BOOLEAN NOT NULL DEFAULT true,
+        created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+        modified_at TIMESTAMP WITH TIME ZONE NOT NULL
+      );
+      `,
+		},
+		{
+			Version:     47,
+			Description: "Track which posts have had their links saved",
+			Script: `
+      ALTER TABLE posts ADD COLUMN links_saved BOOLEAN NOT NULL DEFAULT false;
+      `,
+		},
+		{
+			Version:     48,
+			Description: "Track when comments were first approved",
+			Script: `
+      ALTER TABLE comments ADD COLUMN approved_at TIMESTAMP WITH TIME ZONE;
+      UPDATE comments SET approved_at = COALESCE(modified_at, created_at) WHERE status = 'approved';
+      `,
+		},
 	}
 )
 
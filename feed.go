@@ -0,0 +1,70 @@
+package graphql
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+const (
+	// FeedTitle is the title used for all generated feeds.
+	FeedTitle = "Nat Welch"
+
+	// FeedLink is the site that all generated feeds point to.
+	FeedLink = "https://writing.natwelch.com"
+)
+
+// NewFeed turns a list of posts into a feed that can be rendered as Atom, RSS
+// or JSON Feed. Posts that are not yet published are skipped. The feed's
+// updated time is the most recent modification time of any post in it.
+func NewFeed(title, link string, posts []*Post) *feeds.Feed {
+	f := &feeds.Feed{
+		Title:  title,
+		Link:   &feeds.Link{Href: link},
+		Id:     link,
+		Author: &feeds.Author{Name: "Nat Welch"},
+	}
+
+	now := time.Now()
+	for _, p := range posts {
+		if p == nil || p.Draft || p.Datetime.After(now) {
+			continue
+		}
+
+		uri := p.URI().String()
+		f.Add(&feeds.Item{
+			Title:       p.Title,
+			Link:        &feeds.Link{Href: uri},
+			Id:          uri,
+			Description: p.Summary(),
+			Content:     string(p.HTML()),
+			Created:     p.Datetime,
+			Updated:     p.Modified,
+		})
+
+		if p.Modified.After(f.Updated) {
+			f.Updated = p.Modified
+		}
+	}
+
+	if f.Updated.IsZero() {
+		f.Updated = now
+	}
+	f.Created = f.Updated
+
+	return f
+}
+
+// FeedETag returns a strong ETag for a feed, built from its items and updated
+// time, so identical content always produces the same tag.
+func FeedETag(format string, f *feeds.Feed) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%d", format, f.Id, f.Updated.UnixNano())
+	for _, i := range f.Items {
+		fmt.Fprintf(h, "|%s|%d", i.Id, i.Updated.UnixNano())
+	}
+
+	return fmt.Sprintf(`"%x"`, h.Sum(nil)[:16])
+}
@@ -0,0 +1,37 @@
+package graphql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewFeed(t *testing.T) {
+	now := time.Now()
+	posts := []*Post{
+		{ID: "3", Title: "Future", Content: "soon", Datetime: now.Add(time.Hour), Modified: now},
+		{ID: "2", Title: "Edited", Content: "hello #world", Datetime: now.Add(-time.Hour), Modified: now.Add(-time.Minute)},
+		{ID: "1", Title: "Old", Content: "first", Datetime: now.Add(-48 * time.Hour), Modified: now.Add(-24 * time.Hour)},
+		{ID: "4", Title: "Draft", Content: "wip", Draft: true, Datetime: now.Add(-time.Hour), Modified: now},
+	}
+
+	f := NewFeed(FeedTitle, FeedLink, posts)
+	if len(f.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(f.Items))
+	}
+
+	if !f.Updated.Equal(posts[1].Modified) {
+		t.Errorf("expected feed updated %v, got %v", posts[1].Modified, f.Updated)
+	}
+
+	if got, want := f.Items[0].Id, "https://writing.natwelch.com/post/2"; got != want {
+		t.Errorf("expected item id %q, got %q", want, got)
+	}
+
+	if FeedETag("atom", f) == FeedETag("rss", f) {
+		t.Error("expected etags to differ between formats")
+	}
+
+	if FeedETag("atom", f) != FeedETag("atom", NewFeed(FeedTitle, FeedLink, posts)) {
+		t.Error("expected etags to be stable")
+	}
+}
@@ -61,6 +61,10 @@ func (r *mutationResolver) UpsertStat(ctx context.Context, input NewStat) (*Stat
 		Value: input.Value,
 	}
 
+	if input.When != nil {
+		s.When = *input.When
+	}
+
 	if err := s.Save(ctx); err != nil {
 		return nil, err
 	}
@@ -68,6 +72,23 @@ func (r *mutationResolver) UpsertStat(ctx context.Context, input NewStat) (*Stat
 	return s, nil
 }
 
+// UpsertStats is the resolver for the upsertStats field.
+func (r *mutationResolver) UpsertStats(ctx context.Context, input []*NewStat) ([]*Stat, error) {
+	stats := make([]*Stat, len(input))
+	for i, in := range input {
+		stats[i] = &Stat{Key: in.Key, Value: in.Value}
+		if in.When != nil {
+			stats[i].When = *in.When
+		}
+	}
+
+	if err := SaveStats(ctx, stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
 // UpsertTweet is the resolver for the upsertTweet field.
 func (r *mutationResolver) UpsertTweet(ctx context.Context, input NewTweet) (*Tweet, error) {
 	t := &Tweet{
@@ -132,12 +153,22 @@ func (r *queryResolver) Stats(ctx context.Context, count *int) ([]*Stat, error)
 		}
 	}
 
-	return GetStats(ctx, limit)
+	return GetStats(ctx, limit, CanSeePrivateStats(ctx))
 }
 
 // Stat is the resolver for the stat field.
 func (r *queryResolver) Stat(ctx context.Context, key string, input *Limit) ([]*Stat, error) {
 	limit, offset := ParseLimit(input, 10, 0)
+
+	hidden, err := hiddenStatKeys(ctx, []string{key})
+	if err != nil {
+		return nil, err
+	}
+
+	if hidden[key] {
+		return []*Stat{}, nil
+	}
+
 	return GetStat(ctx, key, limit, offset)
 }
 
@@ -0,0 +1,378 @@
+package graphql
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/form3tech-oss/jwt-go"
+)
+
+// maxJWKSBody is the most of a JWKS or discovery document that is read.
+const maxJWKSBody = 1 << 20
+
+// DefaultJWKSMinRefresh is how long a JWKS waits between refreshes caused by
+// tokens with unknown key IDs, so bad tokens can't hammer the provider.
+const DefaultJWKSMinRefresh = time.Minute
+
+// JWK is a single JSON Web Key, as described in RFC 7517. Only the fields
+// needed for RSA and EC signing keys are parsed.
+type JWK struct {
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid"`
+	Use string   `json:"use"`
+	Alg string   `json:"alg"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	Crv string   `json:"crv"`
+	X   string   `json:"x"`
+	Y   string   `json:"y"`
+	X5c []string `json:"x5c"`
+}
+
+// PublicKey returns the *rsa.PublicKey or *ecdsa.PublicKey the JWK
+// describes.
+func (k *JWK) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		if k.N == "" || k.E == "" {
+			return k.certKey()
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("bad modulus in key %q: %w", k.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("bad exponent in key %q: %w", k.Kid, err)
+		}
+
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 2 || exp.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("bad exponent in key %q", k.Kid)
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		if k.X == "" || k.Y == "" {
+			return k.certKey()
+		}
+
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q in key %q", k.Crv, k.Kid)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("bad x in key %q: %w", k.Kid, err)
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("bad y in key %q: %w", k.Kid, err)
+		}
+
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("key %q is not on curve %s", k.Kid, k.Crv)
+		}
+
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q in key %q", k.Kty, k.Kid)
+	}
+}
+
+// certKey returns the public key of the first certificate in the key's x5c
+// chain. Auth0 used to only publish keys this way.
+func (k *JWK) certKey() (interface{}, error) {
+	if len(k.X5c) == 0 {
+		return nil, fmt.Errorf("key %q has no key material", k.Kid)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(k.X5c[0])
+	if err != nil {
+		return nil, fmt.Errorf("bad certificate in key %q: %w", k.Kid, err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("bad certificate in key %q: %w", k.Kid, err)
+	}
+
+	switch cert.PublicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return cert.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported certificate key in key %q", k.Kid)
+	}
+}
+
+// JWKS is a cache of the signing keys an OpenID Connect provider publishes.
+// Keys are fetched when first needed, again whenever a token is signed with a
+// key ID that isn't cached, and every interval by Run so rotated keys are
+// picked up and retired ones dropped.
+type JWKS struct {
+	// URL is where the keys are published. If it is empty, it is found from
+	// the issuer's /.well-known/openid-configuration document.
+	URL    string
+	Issuer string
+
+	// MinRefresh is the least time between refreshes caused by unknown key
+	// IDs. It defaults to DefaultJWKSMinRefresh.
+	MinRefresh time.Duration
+
+	client Fetcher
+	now    func() time.Time
+
+	// refresh is held while keys are fetched, so concurrent requests with a
+	// new key ID only fetch once.
+	refresh sync.Mutex
+
+	mu      sync.RWMutex
+	keys    map[string]interface{}
+	fetched time.Time
+}
+
+// NewJWKS returns an empty key cache for the keys at url, or for issuer's
+// keys if url is empty.
+func NewJWKS(client Fetcher, url, issuer string) *JWKS {
+	return &JWKS{
+		URL:        url,
+		Issuer:     issuer,
+		MinRefresh: DefaultJWKSMinRefresh,
+		client:     client,
+		now:        time.Now,
+	}
+}
+
+// Key returns the key with the ID kid. If it isn't cached, the keys are
+// refreshed, at most once every MinRefresh.
+func (j *JWKS) Key(ctx context.Context, kid string) (interface{}, error) {
+	if key, ok := j.cached(kid); ok {
+		return key, nil
+	}
+
+	j.refresh.Lock()
+	defer j.refresh.Unlock()
+
+	// Another request may have fetched the key while we waited.
+	if key, ok := j.cached(kid); ok {
+		return key, nil
+	}
+
+	j.mu.RLock()
+	fetched := j.fetched
+	j.mu.RUnlock()
+
+	if fetched.IsZero() || j.now().Sub(fetched) >= j.MinRefresh {
+		if err := j.fetch(ctx); err != nil {
+			return nil, err
+		}
+
+		if key, ok := j.cached(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (j *JWKS) cached(kid string) (interface{}, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// Refresh fetches the keys, replacing every cached key.
+func (j *JWKS) Refresh(ctx context.Context) error {
+	j.refresh.Lock()
+	defer j.refresh.Unlock()
+
+	return j.fetch(ctx)
+}
+
+// Run refreshes the keys every interval until the context is done. Failed
+// refreshes keep the keys already cached.
+func (j *JWKS) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := j.Refresh(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fetch gets and parses the keys. It must be called with refresh held.
+func (j *JWKS) fetch(ctx context.Context) error {
+	if j.URL == "" {
+		u, err := DiscoverJWKSURL(ctx, j.client, j.Issuer)
+		if err != nil {
+			return err
+		}
+		j.URL = u
+	}
+
+	var set struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := getJSON(ctx, j.client, j.URL, &set); err != nil {
+		return fmt.Errorf("could not get keys: %w", err)
+	}
+
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.PublicKey()
+		if err != nil {
+			// One unusable key shouldn't stop us from using the rest.
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("no usable signing keys at %s", j.URL)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.keys = keys
+	j.fetched = j.now()
+
+	return nil
+}
+
+// DiscoverJWKSURL returns the jwks_uri from an OpenID Connect issuer's
+// discovery document.
+func DiscoverJWKSURL(ctx context.Context, client Fetcher, issuer string) (string, error) {
+	if issuer == "" {
+		return "", fmt.Errorf("no JWKS URL or issuer to discover it from")
+	}
+
+	var config struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	u := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, client, u, &config); err != nil {
+		return "", fmt.Errorf("could not discover JWKS URL: %w", err)
+	}
+
+	if config.JWKSURI == "" {
+		return "", fmt.Errorf("no jwks_uri at %s", u)
+	}
+
+	return config.JWKSURI, nil
+}
+
+func getJSON(ctx context.Context, client Fetcher, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxJWKSBody)).Decode(v)
+}
+
+// TokenVerifier checks JWTs from an OpenID Connect provider.
+type TokenVerifier struct {
+	Issuer string
+
+	// Audience is the aud every token must have. If empty, it isn't checked.
+	Audience string
+
+	Keys *JWKS
+}
+
+// Keyfunc is a jwt.Keyfunc that checks a token's issuer and audience, then
+// returns the key it was signed with. The key's type must match the token's
+// signing method, so a token can't pick an algorithm that the key wasn't
+// meant for.
+func (v *TokenVerifier) Keyfunc(token *jwt.Token) (interface{}, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("unexpected claims type %T", token.Claims)
+	}
+
+	if v.Audience != "" && !claims.VerifyAudience(v.Audience, true) {
+		return nil, fmt.Errorf("invalid audience")
+	}
+
+	if v.Issuer != "" && !claims.VerifyIssuer(v.Issuer, true) {
+		return nil, fmt.Errorf("invalid issuer")
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, err := v.Keys.Key(context.Background(), kid)
+	if err != nil {
+		return nil, err
+	}
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if _, ok := key.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := key.(*ecdsa.PublicKey); ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("key %q can't verify %s tokens", kid, token.Method.Alg())
+}
+
+// Verify parses a token and checks its signature and claims.
+func (v *TokenVerifier) Verify(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(tokenString, claims, v.Keyfunc); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,256 @@
+package graphql
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/form3tech-oss/jwt-go"
+)
+
+// testProvider is a local OpenID Connect provider that serves a discovery
+// document and a JWKS that can be swapped out.
+type testProvider struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	keys     []JWK
+	requests int
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	p := &testProvider{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": p.URL + "/", "jwks_uri": p.URL + "/jwks.json"})
+	})
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.requests++
+		json.NewEncoder(w).Encode(map[string][]JWK{"keys": p.keys})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func (p *testProvider) setKeys(keys ...JWK) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = keys
+}
+
+func (p *testProvider) jwksRequests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(t *testing.T, kid string) (*rsa.PrivateKey, JWK) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, JWK{Kty: "RSA", Kid: kid, Use: "sig", N: b64(key.N.Bytes()), E: b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(t *testing.T, kid string) (*ecdsa.PrivateKey, JWK) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, JWK{Kty: "EC", Kid: kid, Crv: "P-256", X: b64(key.X.Bytes()), Y: b64(key.Y.Bytes())}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestTokenVerifier(t *testing.T) {
+	p := newTestProvider(t)
+	rsaKey, rsaPub := rsaJWK(t, "rsa")
+	ecKey, ecPub := ecJWK(t, "ec")
+	p.setKeys(rsaPub, ecPub, JWK{Kty: "RSA", Kid: "enc", Use: "enc", N: rsaPub.N, E: rsaPub.E})
+
+	v := &TokenVerifier{
+		Issuer:   p.URL + "/",
+		Audience: "https://natwelch.com",
+		Keys:     NewJWKS(p.Client(), "", p.URL+"/"),
+	}
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": "user",
+			"iss": p.URL + "/",
+			"aud": []string{"https://natwelch.com", "other"},
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	tests := map[string]struct {
+		token func() string
+		ok    bool
+	}{
+		"rsa": {ok: true, token: func() string {
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, valid())
+		}},
+		"ec": {ok: true, token: func() string {
+			return sign(t, jwt.SigningMethodES256, "ec", ecKey, valid())
+		}},
+		"string audience": {ok: true, token: func() string {
+			c := valid()
+			c["aud"] = "https://natwelch.com"
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, c)
+		}},
+		"wrong audience": {token: func() string {
+			c := valid()
+			c["aud"] = "https://example.com"
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, c)
+		}},
+		"no audience": {token: func() string {
+			c := valid()
+			delete(c, "aud")
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, c)
+		}},
+		"wrong issuer": {token: func() string {
+			c := valid()
+			c["iss"] = "https://example.com/"
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, c)
+		}},
+		"expired": {token: func() string {
+			c := valid()
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, c)
+		}},
+		"wrong key": {token: func() string {
+			other, _ := rsaJWK(t, "rsa")
+			return sign(t, jwt.SigningMethodRS256, "rsa", other, valid())
+		}},
+		"key type mismatch": {token: func() string {
+			return sign(t, jwt.SigningMethodES256, "rsa", ecKey, valid())
+		}},
+		"encryption key": {token: func() string {
+			return sign(t, jwt.SigningMethodRS256, "enc", rsaKey, valid())
+		}},
+		"hmac": {token: func() string {
+			return sign(t, jwt.SigningMethodHS256, "rsa", []byte(rsaPub.N), valid())
+		}},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			claims, err := v.Verify(tc.token())
+			if tc.ok && err != nil {
+				t.Fatalf("Verify() = %v, want ok", err)
+			}
+
+			if !tc.ok && err == nil {
+				t.Fatal("Verify() succeeded, want error")
+			}
+
+			if tc.ok && claims["sub"] != "user" {
+				t.Errorf("sub = %v, want user", claims["sub"])
+			}
+		})
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	p := newTestProvider(t)
+	_, oldPub := rsaJWK(t, "old")
+	newKey, newPub := rsaJWK(t, "new")
+	p.setKeys(oldPub)
+
+	now := time.Now()
+	j := NewJWKS(p.Client(), p.URL+"/jwks.json", "")
+	j.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := j.Key(ctx, "old"); err != nil {
+		t.Fatalf("Key(old) = %v", err)
+	}
+
+	if _, err := j.Key(ctx, "old"); err != nil {
+		t.Fatalf("Key(old) = %v", err)
+	}
+
+	if got := p.jwksRequests(); got != 1 {
+		t.Errorf("cached key made %d requests, want 1", got)
+	}
+
+	// The provider rotates to a new key. An unknown kid refreshes the cache,
+	// but not more than once every MinRefresh.
+	p.setKeys(oldPub, newPub)
+	if _, err := j.Key(ctx, "new"); err == nil {
+		t.Error("Key(new) succeeded before MinRefresh passed")
+	}
+
+	now = now.Add(j.MinRefresh)
+	key, err := j.Key(ctx, "new")
+	if err != nil {
+		t.Fatalf("Key(new) = %v", err)
+	}
+
+	if !newKey.PublicKey.Equal(key) {
+		t.Error("Key(new) returned the wrong key")
+	}
+
+	if _, err := j.Key(ctx, "missing"); err == nil {
+		t.Error("Key(missing) succeeded")
+	}
+
+	if got := p.jwksRequests(); got != 2 {
+		t.Errorf("made %d requests, want 2", got)
+	}
+
+	// Background refreshes drop retired keys.
+	p.setKeys(newPub)
+	if err := j.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() = %v", err)
+	}
+
+	if _, err := j.Key(ctx, "old"); err == nil {
+		t.Error("Key(old) succeeded after it was retired")
+	}
+
+	// A failed refresh keeps the cached keys.
+	p.setKeys()
+	if err := j.Refresh(ctx); err == nil {
+		t.Error("Refresh() with no keys succeeded")
+	}
+
+	if _, err := j.Key(ctx, "new"); err != nil {
+		t.Errorf("Key(new) after failed refresh = %v", err)
+	}
+}
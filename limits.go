@@ -0,0 +1,359 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"golang.org/x/time/rate"
+)
+
+// DefaultFieldCosts are the complexity costs of fields that are slow to
+// resolve, keyed by "Type.field". Every other field costs 1.
+var DefaultFieldCosts = map[string]int{
+	"Post.related":           20,
+	"Query.search":           10,
+	"Query.searchAll":        20,
+	"Query.homeTimelineURLs": 20,
+}
+
+// maxListMultiplier caps how much a requested limit multiplies the cost of a
+// list's children.
+const maxListMultiplier = 100
+
+// ParseFieldCosts parses a comma separated list of costs, such as
+// "Post.related=20,Query.search=10".
+func ParseFieldCosts(s string) (map[string]int, error) {
+	costs := map[string]int{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || !strings.Contains(kv[0], ".") {
+			return nil, fmt.Errorf("field cost %q is not of the form Type.field=cost", pair)
+		}
+
+		cost, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("field cost %q must be a positive integer", pair)
+		}
+
+		costs[strings.TrimSpace(kv[0])] = cost
+	}
+
+	return costs, nil
+}
+
+type costedSchema struct {
+	graphql.ExecutableSchema
+	costs map[string]int
+}
+
+// WithFieldCosts wraps a schema so the complexity of each field is its cost
+// from costs, or 1, plus the complexity of its children. Fields that take a
+// Limit multiply the complexity of their children by the requested limit.
+func WithFieldCosts(es graphql.ExecutableSchema, costs map[string]int) graphql.ExecutableSchema {
+	return &costedSchema{ExecutableSchema: es, costs: costs}
+}
+
+func (s *costedSchema) Complexity(typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+	cost, ok := s.costs[typeName+"."+fieldName]
+	if !ok {
+		cost = 1
+	}
+
+	return cost + childComplexity*listMultiplier(args), true
+}
+
+// listMultiplier returns the limit requested in a field's input, if any.
+// Arguments are raw values from the query or its variables, so the limit can
+// be any kind of number.
+func listMultiplier(args map[string]interface{}) int {
+	in, ok := args["input"].(map[string]interface{})
+	if !ok {
+		return 1
+	}
+
+	raw, ok := in["limit"]
+	if !ok {
+		raw = in["first"]
+	}
+
+	var limit int64
+	switch v := raw.(type) {
+	case int64:
+		limit = v
+	case int:
+		limit = int64(v)
+	case float64:
+		limit = int64(v)
+	case json.Number:
+		limit, _ = v.Int64()
+	}
+
+	if limit < 1 {
+		return 1
+	}
+
+	return int(math.Min(float64(limit), maxListMultiplier))
+}
+
+// DepthLimit is a gqlgen extension that rejects operations that nest fields
+// deeper than Max. Introspection fields are not counted.
+type DepthLimit struct {
+	Max int
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = DepthLimit{}
+
+// ExtensionName is required by graphql.HandlerExtension.
+func (d DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+// Validate is required by graphql.HandlerExtension.
+func (d DepthLimit) Validate(_ graphql.ExecutableSchema) error {
+	if d.Max < 1 {
+		return fmt.Errorf("max depth must be at least 1")
+	}
+
+	return nil
+}
+
+// MutateOperationContext checks the depth of the operation before it runs.
+func (d DepthLimit) MutateOperationContext(_ context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	op := rc.Doc.Operations.ForName(rc.OperationName)
+	if op == nil {
+		return nil
+	}
+
+	depth := selectionDepth(rc.Doc.Fragments, op.SelectionSet, map[string]bool{})
+	if depth > d.Max {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.Max)
+		errcode.Set(err, "DEPTH_LIMIT_EXCEEDED")
+		err.Extensions["depth"] = depth
+		err.Extensions["maxDepth"] = d.Max
+		return err
+	}
+
+	return nil
+}
+
+// selectionDepth returns how deeply fields are nested in a selection set.
+// Fragments are expanded in place, and visited stops fragment cycles.
+func selectionDepth(fragments ast.FragmentDefinitionList, set ast.SelectionSet, visited map[string]bool) int {
+	deepest := 0
+	for _, sel := range set {
+		var d int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name, "__") {
+				continue
+			}
+			d = 1 + selectionDepth(fragments, sel.SelectionSet, visited)
+		case *ast.InlineFragment:
+			d = selectionDepth(fragments, sel.SelectionSet, visited)
+		case *ast.FragmentSpread:
+			if visited[sel.Name] {
+				continue
+			}
+
+			f := fragments.ForName(sel.Name)
+			if f == nil {
+				continue
+			}
+
+			visited[sel.Name] = true
+			d = selectionDepth(fragments, f.SelectionSet, visited)
+			delete(visited, sel.Name)
+		}
+
+		if d > deepest {
+			deepest = d
+		}
+	}
+
+	return deepest
+}
+
+// RateLimitKind is what a client is rate limited by.
+type RateLimitKind string
+
+const (
+	// RateLimitIP limits anonymous clients by IP address.
+	RateLimitIP RateLimitKind = "ip"
+
+	// RateLimitUser limits clients logged in with a JWT by user.
+	RateLimitUser RateLimitKind = "user"
+
+	// RateLimitAPIKey limits clients using the X-API-AUTH header by key.
+	RateLimitAPIKey RateLimitKind = "apikey"
+)
+
+// RateLimitKey identifies a client for rate limiting.
+type RateLimitKey struct {
+	Kind RateLimitKind
+	ID   string
+}
+
+// WithRateLimitKey puts the client's rate limit key in the context.
+func WithRateLimitKey(ctx context.Context, k RateLimitKey) context.Context {
+	return context.WithValue(ctx, rateLimitCtxKey, k)
+}
+
+// GetRateLimitKeyFromContext finds the rate limit key in the context, which
+// is usually inserted by WithRateLimitKey.
+func GetRateLimitKeyFromContext(ctx context.Context) (RateLimitKey, bool) {
+	k, ok := ctx.Value(rateLimitCtxKey).(RateLimitKey)
+	return k, ok
+}
+
+// RateLimit is how many operations a client can make per second, and how
+// many it can make at once after being idle.
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// ParseRateLimit parses a rate limit of the form "per second/burst", such as
+// "5/20".
+func ParseRateLimit(s string) (RateLimit, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return RateLimit{}, fmt.Errorf("rate limit %q is not of the form persecond/burst", s)
+	}
+
+	per, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || per <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q has an invalid rate", s)
+	}
+
+	burst, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || burst < 1 {
+		return RateLimit{}, fmt.Errorf("rate limit %q has an invalid burst", s)
+	}
+
+	return RateLimit{PerSecond: per, Burst: burst}, nil
+}
+
+// RateLimiter is a gqlgen extension that gives every client a token bucket,
+// and rejects operations from clients whose bucket is empty. Clients are
+// identified by the RateLimitKey in the context. Operations without one are
+// not limited.
+type RateLimiter struct {
+	Limits map[RateLimitKind]RateLimit
+
+	mu       sync.Mutex
+	buckets  map[RateLimitKey]*rate.Limiter
+	lastSeen map[RateLimitKey]time.Time
+	now      func() time.Time
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = &RateLimiter{}
+
+// maxBuckets is how many clients are tracked before idle ones are forgotten.
+const maxBuckets = 10000
+
+// NewRateLimiter creates a RateLimiter with limits for each kind of client.
+// Kinds without a limit are not limited.
+func NewRateLimiter(limits map[RateLimitKind]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		Limits:   limits,
+		buckets:  map[RateLimitKey]*rate.Limiter{},
+		lastSeen: map[RateLimitKey]time.Time{},
+		now:      time.Now,
+	}
+}
+
+// ExtensionName is required by graphql.HandlerExtension.
+func (l *RateLimiter) ExtensionName() string {
+	return "RateLimiter"
+}
+
+// Validate is required by graphql.HandlerExtension.
+func (l *RateLimiter) Validate(_ graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext takes a token from the client's bucket.
+func (l *RateLimiter) MutateOperationContext(ctx context.Context, _ *graphql.OperationContext) *gqlerror.Error {
+	k, ok := GetRateLimitKeyFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	wait, ok := l.Allow(k)
+	if ok {
+		return nil
+	}
+
+	retry := int(math.Ceil(wait.Seconds()))
+	err := gqlerror.Errorf("rate limit exceeded, retry in %d seconds", retry)
+	errcode.Set(err, "RATE_LIMITED")
+	err.Extensions["retryAfter"] = retry
+	err.Extensions["limitedBy"] = string(k.Kind)
+
+	return err
+}
+
+// Allow takes a token from the client's bucket. If there are none, it
+// returns false and how long until there will be one.
+func (l *RateLimiter) Allow(k RateLimitKey) (time.Duration, bool) {
+	limit, ok := l.Limits[k.Kind]
+	if !ok {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[k]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.forgetIdle(now)
+		}
+
+		b = rate.NewLimiter(rate.Limit(limit.PerSecond), limit.Burst)
+		l.buckets[k] = b
+	}
+	l.lastSeen[k] = now
+
+	r := b.ReserveN(now, 1)
+	if d := r.DelayFrom(now); d > 0 {
+		r.CancelAt(now)
+		return d, false
+	}
+
+	return 0, true
+}
+
+// forgetIdle drops buckets that have been idle long enough to be full again,
+// as a new bucket would be the same.
+func (l *RateLimiter) forgetIdle(now time.Time) {
+	for k, seen := range l.lastSeen {
+		limit := l.Limits[k.Kind]
+		full := time.Duration(float64(limit.Burst) / limit.PerSecond * float64(time.Second))
+		if now.Sub(seen) >= full {
+			delete(l.buckets, k)
+			delete(l.lastSeen, k)
+		}
+	}
+}
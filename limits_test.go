@@ -0,0 +1,159 @@
+package graphql
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/vektah/gqlparser/v2"
+)
+
+func TestFieldCostComplexity(t *testing.T) {
+	es := WithFieldCosts(NewExecutableSchema(New()), DefaultFieldCosts)
+	tests := map[string]struct {
+		query string
+		want  int
+	}{
+		"simple":  {query: `{ posts { id title } }`, want: 3},
+		"limit":   {query: `{ posts(input: {limit: 10}) { id title } }`, want: 21},
+		"related": {query: `{ posts(input: {limit: 10}) { related(input: {limit: 10}) { id } } }`, want: 1 + 10*(20+10*1)},
+		"search":  {query: `{ search(query: "hi") { id } }`, want: 11},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			doc, errs := gqlparser.LoadQuery(es.Schema(), tc.query)
+			if errs != nil {
+				t.Fatalf("LoadQuery: %v", errs)
+			}
+
+			if got := complexity.Calculate(es, doc.Operations[0], nil); got != tc.want {
+				t.Errorf("complexity = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestListMultiplier(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]interface{}
+		want int
+	}{
+		"none":     {args: map[string]interface{}{}, want: 1},
+		"literal":  {args: map[string]interface{}{"input": map[string]interface{}{"limit": int64(10)}}, want: 10},
+		"variable": {args: map[string]interface{}{"input": map[string]interface{}{"limit": json.Number("25")}}, want: 25},
+		"page":     {args: map[string]interface{}{"input": map[string]interface{}{"first": int64(5)}}, want: 5},
+		"capped":   {args: map[string]interface{}{"input": map[string]interface{}{"limit": int64(5000)}}, want: maxListMultiplier},
+		"negative": {args: map[string]interface{}{"input": map[string]interface{}{"limit": int64(-1)}}, want: 1},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := listMultiplier(tc.args); got != tc.want {
+				t.Errorf("listMultiplier() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSelectionDepth(t *testing.T) {
+	es := NewExecutableSchema(New())
+	tests := map[string]struct {
+		query string
+		want  int
+	}{
+		"flat":          {query: `{ posts { id } }`, want: 2},
+		"nested":        {query: `{ posts { related { related { id } } } }`, want: 4},
+		"fragment":      {query: `{ posts { ...p } } fragment p on Post { related { id } }`, want: 3},
+		"introspection": {query: `{ __schema { types { fields { type { name } } } } }`, want: 0},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			doc, errs := gqlparser.LoadQuery(es.Schema(), tc.query)
+			if errs != nil {
+				t.Fatalf("LoadQuery: %v", errs)
+			}
+
+			if got := selectionDepth(doc.Fragments, doc.Operations[0].SelectionSet, map[string]bool{}); got != tc.want {
+				t.Errorf("selectionDepth = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFieldCosts(t *testing.T) {
+	got, err := ParseFieldCosts("Post.related=50, Query.search=5")
+	if err != nil {
+		t.Fatalf("ParseFieldCosts: %v", err)
+	}
+
+	if got["Post.related"] != 50 || got["Query.search"] != 5 || len(got) != 2 {
+		t.Errorf("ParseFieldCosts() = %v", got)
+	}
+
+	for _, bad := range []string{"related=5", "Post.related=x", "Post.related=-1"} {
+		if _, err := ParseFieldCosts(bad); err == nil {
+			t.Errorf("ParseFieldCosts(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	got, err := ParseRateLimit("0.5/10")
+	if err != nil {
+		t.Fatalf("ParseRateLimit: %v", err)
+	}
+
+	if got.PerSecond != 0.5 || got.Burst != 10 {
+		t.Errorf("ParseRateLimit() = %+v", got)
+	}
+
+	for _, bad := range []string{"5", "0/10", "5/0", "a/b"} {
+		if _, err := ParseRateLimit(bad); err == nil {
+			t.Errorf("ParseRateLimit(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(map[RateLimitKind]RateLimit{RateLimitIP: {PerSecond: 1, Burst: 2}})
+	l.now = func() time.Time { return now }
+
+	ip := RateLimitKey{Kind: RateLimitIP, ID: "127.0.0.1"}
+	for i := 0; i < 2; i++ {
+		if _, ok := l.Allow(ip); !ok {
+			t.Fatalf("request %d was limited", i)
+		}
+	}
+
+	wait, ok := l.Allow(ip)
+	if ok {
+		t.Fatal("third request was allowed")
+	}
+
+	if wait != time.Second {
+		t.Errorf("wait = %v, want 1s", wait)
+	}
+
+	if _, ok := l.Allow(RateLimitKey{Kind: RateLimitIP, ID: "10.0.0.1"}); !ok {
+		t.Error("other IP was limited")
+	}
+
+	if _, ok := l.Allow(RateLimitKey{Kind: RateLimitUser, ID: "user"}); !ok {
+		t.Error("unlimited kind was limited")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := l.Allow(ip); !ok {
+		t.Error("request after refill was limited")
+	}
+}
@@ -33,14 +33,27 @@ func (l *Link) Save(ctx context.Context) error {
 
 	l.Modified = time.Now()
 
-	if _, err := db.ExecContext(
+	tags, err := CanonicalTags(ctx, db, l.Tags)
+	if err != nil {
+		return err
+	}
+	l.Tags = tags
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
 		ctx,
 		`
 INSERT INTO links(title, uri, description, created, created_at, modified_at, tags)
 VALUES ($1, $2, $3, $4, $6, $6, $5)
 ON CONFLICT (uri) DO UPDATE
 SET (title, description, created, modified_at, tags) = ($1, $3, $4, $6, $5)
-WHERE links.uri = $2;
+WHERE links.uri = $2
+RETURNING id;
 `,
 		l.Title,
 		l.URI,
@@ -48,17 +61,39 @@ WHERE links.uri = $2;
 		l.Created,
 		pq.Array(l.Tags),
 		time.Now(),
-	); err != nil {
+	)
+	if err := row.Scan(&l.ID); err != nil {
+		return err
+	}
+
+	if err := enqueueEvent(ctx, tx, WebhookEventLinkUpserted, l); err != nil {
 		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // IsLinkable exists to show that this method implements the Linkable type in
 // graphql.
 func (l *Link) IsLinkable() {}
 
+// IsSearchable exists to show that this method implements the Searchable
+// type in graphql.
+func (l *Link) IsSearchable() {}
+
+// Summary returns the description of a link, or its title if it has none.
+func (l *Link) Summary() string {
+	if l.Description != "" {
+		return l.Description
+	}
+
+	return l.Title
+}
+
+func (l *Link) GetSummary() string {
+	return l.Summary()
+}
+
 // GetLinkByURI gets a link by uri from the database.
 func GetLinkByURI(ctx context.Context, uri string) (*Link, error) {
 	var link Link
@@ -124,3 +159,56 @@ func GetLinks(ctx context.Context, limit int, offset int) ([]*Link, error) {
 
 	return links, nil
 }
+
+// GetLinksConnection returns a page of links, newest first, starting after the
+// cursor.
+func GetLinksConnection(ctx context.Context, first int, after *Cursor) (*LinkConnection, error) {
+	t, id, err := after.uuidArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+  SELECT id, title, uri, description, created, modified_at, tags
+  FROM links
+  WHERE ($1::timestamptz IS NULL OR (created, id) < ($1::timestamptz, $2::uuid))
+  ORDER BY created DESC, id DESC
+  LIMIT $3`,
+		t,
+		id,
+		first+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conn := &LinkConnection{Edges: []*LinkEdge{}}
+	var last *Cursor
+	count := 0
+	for rows.Next() {
+		link := new(Link)
+		err := rows.Scan(&link.ID, &link.Title, &link.URI, &link.Description, &link.Created, &link.Modified, pq.Array(&link.Tags))
+		if err != nil {
+			return nil, err
+		}
+
+		count++
+		if count > first {
+			break
+		}
+
+		last = &Cursor{Time: link.Created, ID: link.ID}
+		conn.Edges = append(conn.Edges, &LinkEdge{Cursor: last.String(), Node: link})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	conn.PageInfo = newPageInfo(count, first, last)
+
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM links").Scan(&conn.TotalCount); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
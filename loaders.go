@@ -0,0 +1,208 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vikstrous/dataloadgen"
+)
+
+// Loaders batch up the lookups made while resolving a single request, so that
+// resolving a list of N items makes a constant number of queries instead of N.
+// A new set of Loaders should be made for every request, as they cache
+// results for their lifetime.
+type Loaders struct {
+	Users  *dataloadgen.Loader[string, *User]
+	Posts  *dataloadgen.Loader[int64, *Post]
+	Tweets *dataloadgen.Loader[string, *Tweet]
+	Links  *dataloadgen.Loader[string, *Link]
+
+	StatDefinitions *dataloadgen.Loader[string, *StatDefinition]
+}
+
+// NewLoaders creates a set of Loaders for a request.
+func NewLoaders() *Loaders {
+	wait := dataloadgen.WithWait(2 * time.Millisecond)
+	return &Loaders{
+		Users:  dataloadgen.NewLoader(fetchUsers, wait),
+		Posts:  dataloadgen.NewLoader(fetchPosts, wait),
+		Tweets: dataloadgen.NewLoader(fetchTweets, wait),
+		Links:  dataloadgen.NewLoader(fetchLinks, wait),
+
+		StatDefinitions: dataloadgen.NewLoader(fetchStatDefinitions, wait),
+	}
+}
+
+// WithLoaders puts loaders in the context.
+func WithLoaders(ctx context.Context, l *Loaders) context.Context {
+	return context.WithValue(ctx, loadersCtxKey, l)
+}
+
+// GetLoadersFromContext finds the loaders in the context. This is usually
+// inserted by WithLoaders. If there are none, a new set is returned, which
+// will not batch with any other lookups.
+func GetLoadersFromContext(ctx context.Context) *Loaders {
+	l, ok := ctx.Value(loadersCtxKey).(*Loaders)
+	if !ok {
+		return NewLoaders()
+	}
+
+	return l
+}
+
+// LoadUser gets a user by ID, batched with other lookups in the request.
+func LoadUser(ctx context.Context, id string) (*User, error) {
+	return GetLoadersFromContext(ctx).Users.Load(ctx, id)
+}
+
+// LoadPost gets a post by ID, batched with other lookups in the request.
+func LoadPost(ctx context.Context, id int64) (*Post, error) {
+	return GetLoadersFromContext(ctx).Posts.Load(ctx, id)
+}
+
+// LoadTweets gets tweets by ID, batched with other lookups in the request.
+func LoadTweets(ctx context.Context, ids []string) ([]*Tweet, error) {
+	return GetLoadersFromContext(ctx).Tweets.LoadAll(ctx, ids)
+}
+
+// LoadLink gets a link by ID, batched with other lookups in the request.
+func LoadLink(ctx context.Context, id string) (*Link, error) {
+	return GetLoadersFromContext(ctx).Links.Load(ctx, id)
+}
+
+// LoadStatDefinition gets the definition of a stat, batched with other
+// lookups in the request. It is nil if the stat has no definition.
+func LoadStatDefinition(ctx context.Context, key string) (*StatDefinition, error) {
+	return GetLoadersFromContext(ctx).StatDefinitions.Load(ctx, key)
+}
+
+// byKeys puts the values that were found in the same order as keys, which is
+// what loaders expect. Missing keys get a nil value, and an error from missing
+// if it isn't nil.
+func byKeys[K comparable, V any](keys []K, found map[K]*V, missing func(K) error) ([]*V, []error) {
+	values := make([]*V, len(keys))
+	var errs []error
+	for i, k := range keys {
+		if v, ok := found[k]; ok {
+			values[i] = v
+			continue
+		}
+
+		if missing != nil {
+			if errs == nil {
+				errs = make([]error, len(keys))
+			}
+			errs[i] = missing(k)
+		}
+	}
+
+	return values, errs
+}
+
+func fetchUsers(ctx context.Context, ids []string) ([]*User, []error) {
+	rows, err := userQuery(ctx, "SELECT id, role, name, disabled_at, created_at, modified_at FROM users WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	found := map[string]*User{}
+	for _, u := range rows {
+		found[u.ID] = u
+	}
+
+	return byKeys(ids, found, func(id string) error { return fmt.Errorf("no user %q", id) })
+}
+
+func fetchPosts(ctx context.Context, ids []int64) ([]*Post, []error) {
+	found, err := postQuery(ctx, `
+SELECT id, title, content, date, created_at, modified_at, tags, draft, slug
+FROM posts
+WHERE id = ANY($1)
+`, pq.Array(ids))
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	byID := map[int64]*Post{}
+	for _, p := range found {
+		byID[p.IntID()] = p
+	}
+
+	// Missing posts are nil, matching GetPost.
+	return byKeys(ids, byID, nil)
+}
+
+func fetchTweets(ctx context.Context, ids []string) ([]*Tweet, []error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, text, hashtags, symbols, user_mentions, urls, screen_name, favorites, retweets, posted FROM tweets WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, []error{err}
+	}
+	defer rows.Close()
+
+	found := map[string]*Tweet{}
+	for rows.Next() {
+		var uris []string
+		tweet := new(Tweet)
+		if err := rows.Scan(&tweet.ID, &tweet.Text, pq.Array(&tweet.Hashtags), pq.Array(&tweet.Symbols), pq.Array(&tweet.UserMentions), pq.Array(&uris), &tweet.ScreenName, &tweet.FavoriteCount, &tweet.RetweetCount, &tweet.Posted); err != nil {
+			return nil, []error{err}
+		}
+
+		for _, v := range uris {
+			tweet.Urls = append(tweet.Urls, NewURI(v))
+		}
+
+		found[tweet.ID] = tweet
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, []error{err}
+	}
+
+	// Missing tweets are nil, as cacophony can know about tweets we have not
+	// archived.
+	return byKeys(ids, found, nil)
+}
+
+func fetchLinks(ctx context.Context, ids []string) ([]*Link, []error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, title, uri, description, created, modified_at, tags FROM links WHERE id = ANY($1::uuid[])", pq.Array(ids))
+	if err != nil {
+		return nil, []error{err}
+	}
+	defer rows.Close()
+
+	found := map[string]*Link{}
+	for rows.Next() {
+		link := new(Link)
+		if err := rows.Scan(&link.ID, &link.Title, &link.URI, &link.Description, &link.Created, &link.Modified, pq.Array(&link.Tags)); err != nil {
+			return nil, []error{err}
+		}
+		found[link.ID] = link
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, []error{err}
+	}
+
+	return byKeys(ids, found, func(id string) error { return fmt.Errorf("no link %q", id) })
+}
+
+func fetchStatDefinitions(ctx context.Context, keys []string) ([]*StatDefinition, []error) {
+	found, err := statDefinitionQuery(ctx, `
+SELECT key, unit, description, precision, min, max, higher_is_better, public, created_at, modified_at
+FROM stat_definitions
+WHERE key = ANY($1)
+`, pq.Array(keys))
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	byKey := map[string]*StatDefinition{}
+	for _, d := range found {
+		byKey[d.Key] = d
+	}
+
+	// Most stats have no definition, so missing ones are nil.
+	return byKeys(keys, byKey, nil)
+}
@@ -0,0 +1,74 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vikstrous/dataloadgen"
+)
+
+func TestByKeys(t *testing.T) {
+	a, b := &User{ID: "a"}, &User{ID: "b"}
+	found := map[string]*User{"a": a, "b": b}
+
+	users, errs := byKeys([]string{"b", "missing", "a"}, found, func(id string) error { return fmt.Errorf("no user %q", id) })
+	if len(users) != 3 || users[0] != b || users[1] != nil || users[2] != a {
+		t.Errorf("byKeys() = %v, want [b nil a]", users)
+	}
+
+	if len(errs) != 3 || errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Errorf("byKeys() errs = %v, want an error for the missing user only", errs)
+	}
+
+	users, errs = byKeys([]string{"missing", "a"}, found, nil)
+	if users[0] != nil || users[1] != a || errs != nil {
+		t.Errorf("byKeys() without missing = %v, %v, want [nil a], nil", users, errs)
+	}
+}
+
+func TestLoaderBatching(t *testing.T) {
+	found := map[string]*Link{"1": {ID: "1"}, "2": {ID: "2"}}
+
+	var mu sync.Mutex
+	var batches [][]string
+	l := dataloadgen.NewLoader(func(_ context.Context, ids []string) ([]*Link, []error) {
+		mu.Lock()
+		batches = append(batches, ids)
+		mu.Unlock()
+		return byKeys(ids, found, func(id string) error { return fmt.Errorf("no link %q", id) })
+	}, dataloadgen.WithWait(10*time.Millisecond))
+
+	ids := []string{"2", "3", "1"}
+	links := make([]*Link, len(ids))
+	errs := make([]error, len(ids))
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			links[i], errs[i] = l.Load(context.Background(), id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	if len(batches) != 1 || len(batches[0]) != len(ids) {
+		t.Errorf("expected one batch of %d ids, got %v", len(ids), batches)
+	}
+
+	for i, id := range ids {
+		if _, ok := found[id]; ok {
+			if errs[i] != nil || links[i] == nil || links[i].ID != id {
+				t.Errorf("Load(%q) = %+v, %v, want link %q", id, links[i], errs[i], id)
+			}
+			continue
+		}
+
+		if errs[i] == nil {
+			t.Errorf("Load(%q) succeeded, want an error", id)
+		}
+	}
+}
@@ -95,7 +95,7 @@ WHERE logs.id = $1;
 
 // SetUser looks up a user by ID and then sets it for this log.
 func (l *Log) SetUser(ctx context.Context, id string) error {
-	u, err := GetUser(ctx, id)
+	u, err := FindUser(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -181,3 +181,66 @@ func GetLog(ctx context.Context, id string) (*Log, error) {
 		return l, nil
 	}
 }
+
+// UserLogsConnection gets a page of logs for a User, newest first, starting
+// after the cursor.
+func UserLogsConnection(ctx context.Context, u *User, first int, after *Cursor) (*LogConnection, error) {
+	if u == nil {
+		return nil, fmt.Errorf("no user specified")
+	}
+
+	t, id := after.args()
+	rows, err := db.QueryContext(
+		ctx, `
+    SELECT id, description, project, sector, started, stopped, user_id, created_at, modified_at
+    FROM logs
+    WHERE user_id = $1
+      AND ($2::timestamptz IS NULL OR (started, id) < ($2::timestamptz, $3::text))
+    ORDER BY started DESC, id DESC
+    LIMIT $4
+    `,
+		u.ID, t, id, first+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conn := &LogConnection{Edges: []*LogEdge{}}
+	var last *Cursor
+	count := 0
+	for rows.Next() {
+		l := &Log{}
+		if err := rows.Scan(
+			&l.ID,
+			&l.Description,
+			&l.Project,
+			&l.Sector,
+			&l.Started,
+			&l.Stopped,
+			&l.User.ID,
+			&l.Created,
+			&l.Modified,
+		); err != nil {
+			return nil, err
+		}
+
+		count++
+		if count > first {
+			break
+		}
+
+		last = &Cursor{Time: l.Started, ID: l.ID}
+		conn.Edges = append(conn.Edges, &LogEdge{Cursor: last.String(), Node: l})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	conn.PageInfo = newPageInfo(count, first, last)
+
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM logs WHERE user_id = $1", u.ID).Scan(&conn.TotalCount); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
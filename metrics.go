@@ -0,0 +1,180 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	// statMetricPrefix starts the name of every metric made from a stat.
+	statMetricPrefix = "stat_"
+
+	// statMetricsLimit is the most stats exported in one scrape.
+	statMetricsLimit = 10000
+
+	// statMetricsTimeout is how long a scrape can spend reading stats.
+	statMetricsTimeout = 10 * time.Second
+)
+
+// StatLabelRule exports every stat whose key matches Pattern as one metric,
+// with the part of the key matched by the pattern's "*" as the value of
+// Label. For example, the pattern "weather.*.temp" with the name
+// "weather_temp" and the label "city" exports "weather.sf.temp" as
+// stat_weather_temp{city="sf"}.
+type StatLabelRule struct {
+	Pattern string
+	Name    string
+	Label   string
+}
+
+// match returns the label value for key, and whether the rule covers it.
+func (r StatLabelRule) match(key string) (string, bool) {
+	prefix, suffix, _ := strings.Cut(r.Pattern, "*")
+	if len(key) <= len(prefix)+len(suffix) || !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) {
+		return "", false
+	}
+
+	return key[len(prefix) : len(key)-len(suffix)], true
+}
+
+// ParseStatLabelRules parses a comma separated list of rules, each written
+// as "pattern=name:label", such as "weather.*.temp=weather_temp:city".
+func ParseStatLabelRules(s string) ([]StatLabelRule, error) {
+	var rules []StatLabelRule
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		pattern, target, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("stat label rule %q is missing =", part)
+		}
+
+		name, label, ok := strings.Cut(target, ":")
+		if !ok || name == "" || label == "" {
+			return nil, fmt.Errorf("stat label rule %q must be pattern=name:label", part)
+		}
+
+		if strings.Count(pattern, "*") != 1 {
+			return nil, fmt.Errorf("stat label rule pattern %q must have exactly one *", pattern)
+		}
+
+		rules = append(rules, StatLabelRule{
+			Pattern: pattern,
+			Name:    SanitizeMetricName(name),
+			Label:   SanitizeMetricName(label),
+		})
+	}
+
+	return rules, nil
+}
+
+// SanitizeMetricName turns s into a valid Prometheus metric or label name by
+// replacing every character that isn't a letter, digit or underscore with an
+// underscore, and adding an underscore if it starts with a digit.
+func SanitizeMetricName(s string) string {
+	var b strings.Builder
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+			b.WriteRune(c)
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteByte('_')
+		}
+	}
+
+	return b.String()
+}
+
+// StatCollector is a Prometheus collector that exports the latest value of
+// every public stat as a gauge. Stats are read from the database on every scrape.
+// Values aren't timestamped, since Prometheus drops samples that are much
+// older than the scrape, and most stats change rarely.
+type StatCollector struct {
+	Rules []StatLabelRule
+}
+
+var _ prometheus.Collector = (*StatCollector)(nil)
+
+// Describe sends nothing, because the metrics depend on which stats exist.
+// This makes the collector unchecked.
+func (c *StatCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect exports the latest value of every stat.
+func (c *StatCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statMetricsTimeout)
+	defer cancel()
+
+	stats, err := GetStats(ctx, statMetricsLimit, false)
+	if err != nil {
+		log.Errorw("could not get stats for metrics", zap.Error(err))
+		return
+	}
+
+	for _, m := range c.metrics(stats) {
+		ch <- m
+	}
+}
+
+// metrics turns stats into gauges. If more than one stat ends up with the
+// same name and labels, only the first is kept, because Prometheus rejects
+// duplicates.
+func (c *StatCollector) metrics(stats []*Stat) []prometheus.Metric {
+	descs := map[string]*prometheus.Desc{}
+	seen := map[string]bool{}
+
+	var metrics []prometheus.Metric
+	for _, s := range stats {
+		name, label, value := statMetricPrefix+SanitizeMetricName(s.Key), "", ""
+		for _, r := range c.Rules {
+			if v, ok := r.match(s.Key); ok {
+				name, label, value = statMetricPrefix+r.Name, r.Label, v
+				break
+			}
+		}
+
+		id := name + "\xff" + value
+		if seen[id] {
+			log.Warnw("skipping stat with duplicate metric", "key", s.Key, "metric", name)
+			continue
+		}
+		seen[id] = true
+
+		desc, ok := descs[name]
+		if !ok {
+			var labels []string
+			if label != "" {
+				labels = []string{label}
+			}
+			desc = prometheus.NewDesc(name, "Latest value of a stat.", labels, nil)
+			descs[name] = desc
+		}
+
+		var values []string
+		if label != "" {
+			values = []string{value}
+		}
+
+		m, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, s.Value, values...)
+		if err != nil {
+			log.Warnw("skipping stat that can't be a metric", "key", s.Key, "metric", name, zap.Error(err))
+			continue
+		}
+
+		metrics = append(metrics, m)
+	}
+
+	return metrics
+}
@@ -0,0 +1,98 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestSanitizeMetricName(t *testing.T) {
+	tests := map[string]string{
+		"steps":            "steps",
+		"weather.temp":     "weather_temp",
+		"heart-rate (bpm)": "heart_rate__bpm_",
+		"5k_time":          "_5k_time",
+		"Über":             "_ber",
+	}
+
+	for in, want := range tests {
+		in, want := in, want // capture range variables
+		t.Run(in, func(t *testing.T) {
+			t.Parallel()
+			if got := SanitizeMetricName(in); got != want {
+				t.Errorf("SanitizeMetricName(%q) = %q, want %q", in, got, want)
+			}
+		})
+	}
+}
+
+func TestParseStatLabelRules(t *testing.T) {
+	rules, err := ParseStatLabelRules(" weather.*.temp=weather_temp:city, steps.*=steps:device.id ,")
+	if err != nil {
+		t.Fatalf("ParseStatLabelRules: %v", err)
+	}
+
+	want := []StatLabelRule{
+		{Pattern: "weather.*.temp", Name: "weather_temp", Label: "city"},
+		{Pattern: "steps.*", Name: "steps", Label: "device_id"},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("got %d rules, want %d", len(rules), len(want))
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"weather", "weather.*=temp", "weather.*=:city", "weather=temp:city", "*.*=temp:city"} {
+		if _, err := ParseStatLabelRules(bad); err == nil {
+			t.Errorf("ParseStatLabelRules(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestStatCollectorMetrics(t *testing.T) {
+	c := &StatCollector{Rules: []StatLabelRule{{Pattern: "weather.*.temp", Name: "weather_temp", Label: "city"}}}
+	stats := []*Stat{
+		{Key: "steps", Value: 1000},
+		{Key: "weather.sf.temp", Value: 18.5},
+		{Key: "weather.nyc.temp", Value: 9},
+		{Key: "weather..temp", Value: 1},
+		{Key: "weather-sf-temp", Value: 2},
+		{Key: "weather_sf_temp", Value: 3},
+	}
+
+	got := map[string]float64{}
+	for _, m := range c.metrics(stats) {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+
+		id := m.Desc().String()
+		id = id[strings.Index(id, `fqName: "`)+len(`fqName: "`):]
+		id = id[:strings.Index(id, `"`)]
+		for _, l := range pb.GetLabel() {
+			id += "{" + l.GetName() + "=" + l.GetValue() + "}"
+		}
+		got[id] = pb.GetGauge().GetValue()
+	}
+
+	want := map[string]float64{
+		"stat_steps":                  1000,
+		"stat_weather_temp{city=sf}":  18.5,
+		"stat_weather_temp{city=nyc}": 9,
+		"stat_weather__temp":          1,
+		"stat_weather_sf_temp":        2,
+	}
+	if len(got) != len(want) {
+		t.Errorf("got metrics %v, want %v", got, want)
+	}
+	for id, v := range want {
+		if got[id] != v {
+			t.Errorf("%s = %v, want %v", id, got[id], v)
+		}
+	}
+}
@@ -22,6 +22,37 @@ type Searchable interface {
 type AddComment struct {
 	Content string `json:"content"`
 	PostID  string `json:"post_id"`
+	// parent_id is the comment this is a reply to, if any.
+	ParentID *string `json:"parent_id,omitempty"`
+}
+
+type BookConnection struct {
+	Edges      []*BookEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int         `json:"totalCount"`
+}
+
+type BookEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Book  `json:"node"`
+}
+
+type CommentConnection struct {
+	Edges      []*CommentEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+}
+
+type CommentEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Comment `json:"node"`
+}
+
+// A CreatedAPIKey is a new API key and its secret.
+type CreatedAPIKey struct {
+	// key is the secret to send in the X-API-AUTH header. It can't be retrieved again.
+	Key    string  `json:"key"`
+	APIKey *APIKey `json:"apiKey"`
 }
 
 type EditBook struct {
@@ -36,6 +67,44 @@ type EditPost struct {
 	Title    *string    `json:"title,omitempty"`
 	Datetime *time.Time `json:"datetime,omitempty"`
 	Draft    *bool      `json:"draft,omitempty"`
+	Slug     *string    `json:"slug,omitempty"`
+}
+
+type EditProfile struct {
+	Name string `json:"name"`
+}
+
+type EditStatDefinition struct {
+	Key            string   `json:"key"`
+	Unit           *string  `json:"unit,omitempty"`
+	Description    *string  `json:"description,omitempty"`
+	Precision      *int     `json:"precision,omitempty"`
+	Min            *float64 `json:"min,omitempty"`
+	Max            *float64 `json:"max,omitempty"`
+	HigherIsBetter *bool    `json:"higherIsBetter,omitempty"`
+	// public defaults to true.
+	Public *bool `json:"public,omitempty"`
+}
+
+type EditStatRetentionPolicy struct {
+	Pattern    string `json:"pattern"`
+	RawDays    int    `json:"rawDays"`
+	HourlyDays int    `json:"hourlyDays"`
+	DailyDays  *int   `json:"dailyDays,omitempty"`
+}
+
+type EditTag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// aliases replace the tag's current aliases. If null, they are left as they are.
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+type EditWebhook struct {
+	URL    *URI           `json:"url,omitempty"`
+	Secret *string        `json:"secret,omitempty"`
+	Events []WebhookEvent `json:"events,omitempty"`
+	Active *bool          `json:"active,omitempty"`
 }
 
 type InputGeo struct {
@@ -48,6 +117,34 @@ type Limit struct {
 	Offset *int `json:"offset,omitempty"`
 }
 
+type LinkConnection struct {
+	Edges      []*LinkEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int         `json:"totalCount"`
+}
+
+type LinkEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Link  `json:"node"`
+}
+
+type LogConnection struct {
+	Edges      []*LogEdge `json:"edges"`
+	PageInfo   *PageInfo  `json:"pageInfo"`
+	TotalCount int        `json:"totalCount"`
+}
+
+type LogEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Log   `json:"node"`
+}
+
+type NewAPIKey struct {
+	Name    string     `json:"name"`
+	Scopes  []Scope    `json:"scopes"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
 type NewLink struct {
 	Title       string     `json:"title"`
 	URI         URI        `json:"uri"`
@@ -67,6 +164,8 @@ type NewLog struct {
 type NewStat struct {
 	Key   string  `json:"key"`
 	Value float64 `json:"value"`
+	// when is when the value was measured. It defaults to now.
+	When *time.Time `json:"when,omitempty"`
 }
 
 type NewTweet struct {
@@ -82,11 +181,221 @@ type NewTweet struct {
 	UserMentions  []string  `json:"user_mentions,omitempty"`
 }
 
-// A stat is a key value pair of two interesting strings.
-type Stat struct {
-	Key   string    `json:"key"`
-	Value float64   `json:"value"`
-	When  time.Time `json:"when"`
+type NewWebhook struct {
+	URL    URI            `json:"url"`
+	Secret string         `json:"secret"`
+	Events []WebhookEvent `json:"events"`
+	Active *bool          `json:"active,omitempty"`
+}
+
+// Page is the cursor based replacement for Limit.
+type Page struct {
+	// The number of items to return.
+	First *int `json:"first,omitempty"`
+	// Only return items after this cursor.
+	After *string `json:"after,omitempty"`
+}
+
+// PageInfo describes where a page of a connection sits in the full list.
+type PageInfo struct {
+	HasNextPage bool `json:"hasNextPage"`
+	// endCursor is the cursor of the last edge in this page. Pass it as after to get the next page.
+	EndCursor *string `json:"endCursor,omitempty"`
+}
+
+type PhotoConnection struct {
+	Edges      []*PhotoEdge `json:"edges"`
+	PageInfo   *PageInfo    `json:"pageInfo"`
+	TotalCount int          `json:"totalCount"`
+}
+
+type PhotoEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Photo `json:"node"`
+}
+
+type PostConnection struct {
+	Edges      []*PostEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int         `json:"totalCount"`
+}
+
+type PostEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Post  `json:"node"`
+}
+
+type StatConnection struct {
+	Edges      []*StatEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int         `json:"totalCount"`
+}
+
+type StatEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Stat  `json:"node"`
+}
+
+// A StatPoint is the aggregated value of a stat in a bucket.
+type StatPoint struct {
+	// when is the start of the bucket.
+	When time.Time `json:"when"`
+	// value is null for empty buckets, unless they are filled.
+	Value *float64 `json:"value,omitempty"`
+}
+
+// A StatSeries is the history of a stat, aggregated into buckets.
+type StatSeries struct {
+	Key    string       `json:"key"`
+	Points []*StatPoint `json:"points"`
+}
+
+type TweetConnection struct {
+	Edges      []*TweetEdge `json:"edges"`
+	PageInfo   *PageInfo    `json:"pageInfo"`
+	TotalCount int          `json:"totalCount"`
+}
+
+type TweetEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Tweet `json:"node"`
+}
+
+type AuditStatus string
+
+const (
+	AuditStatusSuccess AuditStatus = "success"
+	AuditStatusFailed  AuditStatus = "failed"
+	// The user wasn't allowed to run the mutation.
+	AuditStatusForbidden AuditStatus = "forbidden"
+)
+
+var AllAuditStatus = []AuditStatus{
+	AuditStatusSuccess,
+	AuditStatusFailed,
+	AuditStatusForbidden,
+}
+
+func (e AuditStatus) IsValid() bool {
+	switch e {
+	case AuditStatusSuccess, AuditStatusFailed, AuditStatusForbidden:
+		return true
+	}
+	return false
+}
+
+func (e AuditStatus) String() string {
+	return string(e)
+}
+
+func (e *AuditStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AuditStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AuditStatus", str)
+	}
+	return nil
+}
+
+func (e AuditStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// AuthMethod is how a request was signed in.
+type AuthMethod string
+
+const (
+	// A JWT in the Authorization header.
+	AuthMethodJwt AuthMethod = "jwt"
+	// An API key in the X-API-AUTH header.
+	AuthMethodAPIKey AuthMethod = "api_key"
+	// Nobody was signed in.
+	AuthMethodNone AuthMethod = "none"
+)
+
+var AllAuthMethod = []AuthMethod{
+	AuthMethodJwt,
+	AuthMethodAPIKey,
+	AuthMethodNone,
+}
+
+func (e AuthMethod) IsValid() bool {
+	switch e {
+	case AuthMethodJwt, AuthMethodAPIKey, AuthMethodNone:
+		return true
+	}
+	return false
+}
+
+func (e AuthMethod) String() string {
+	return string(e)
+}
+
+func (e *AuthMethod) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AuthMethod(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AuthMethod", str)
+	}
+	return nil
+}
+
+func (e AuthMethod) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// CommentStatus is the moderation state of a comment.
+type CommentStatus string
+
+const (
+	CommentStatusPending  CommentStatus = "pending"
+	CommentStatusApproved CommentStatus = "approved"
+	CommentStatusRejected CommentStatus = "rejected"
+	CommentStatusSpam     CommentStatus = "spam"
+)
+
+var AllCommentStatus = []CommentStatus{
+	CommentStatusPending,
+	CommentStatusApproved,
+	CommentStatusRejected,
+	CommentStatusSpam,
+}
+
+func (e CommentStatus) IsValid() bool {
+	switch e {
+	case CommentStatusPending, CommentStatusApproved, CommentStatusRejected, CommentStatusSpam:
+		return true
+	}
+	return false
+}
+
+func (e CommentStatus) String() string {
+	return string(e)
+}
+
+func (e *CommentStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CommentStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CommentStatus", str)
+	}
+	return nil
+}
+
+func (e CommentStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
 
 type Role string
@@ -130,6 +439,111 @@ func (e Role) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
 
+// Scope is something an API key is allowed to do. In docs, the first underscore
+// is replaced with a colon, so stats_write is written as stats:write.
+type Scope string
+
+const (
+	ScopePostsRead   Scope = "posts_read"
+	ScopePostsWrite  Scope = "posts_write"
+	ScopeLinksWrite  Scope = "links_write"
+	ScopeStatsWrite  Scope = "stats_write"
+	ScopeBooksWrite  Scope = "books_write"
+	ScopeTweetsWrite Scope = "tweets_write"
+	ScopeLogsRead    Scope = "logs_read"
+	ScopeLogsWrite   Scope = "logs_write"
+	ScopePhotosRead  Scope = "photos_read"
+	ScopePhotosWrite Scope = "photos_write"
+)
+
+var AllScope = []Scope{
+	ScopePostsRead,
+	ScopePostsWrite,
+	ScopeLinksWrite,
+	ScopeStatsWrite,
+	ScopeBooksWrite,
+	ScopeTweetsWrite,
+	ScopeLogsRead,
+	ScopeLogsWrite,
+	ScopePhotosRead,
+	ScopePhotosWrite,
+}
+
+func (e Scope) IsValid() bool {
+	switch e {
+	case ScopePostsRead, ScopePostsWrite, ScopeLinksWrite, ScopeStatsWrite, ScopeBooksWrite, ScopeTweetsWrite, ScopeLogsRead, ScopeLogsWrite, ScopePhotosRead, ScopePhotosWrite:
+		return true
+	}
+	return false
+}
+
+func (e Scope) String() string {
+	return string(e)
+}
+
+func (e *Scope) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Scope(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Scope", str)
+	}
+	return nil
+}
+
+func (e Scope) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// SearchType is a kind of thing that can be searched for.
+type SearchType string
+
+const (
+	SearchTypePost  SearchType = "post"
+	SearchTypeLink  SearchType = "link"
+	SearchTypeTweet SearchType = "tweet"
+	SearchTypeBook  SearchType = "book"
+)
+
+var AllSearchType = []SearchType{
+	SearchTypePost,
+	SearchTypeLink,
+	SearchTypeTweet,
+	SearchTypeBook,
+}
+
+func (e SearchType) IsValid() bool {
+	switch e {
+	case SearchTypePost, SearchTypeLink, SearchTypeTweet, SearchTypeBook:
+		return true
+	}
+	return false
+}
+
+func (e SearchType) String() string {
+	return string(e)
+}
+
+func (e *SearchType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SearchType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SearchType", str)
+	}
+	return nil
+}
+
+func (e SearchType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
 type Sector string
 
 const (
@@ -178,3 +592,244 @@ func (e *Sector) UnmarshalGQL(v interface{}) error {
 func (e Sector) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
+
+// StatAggregation is how the values in a bucket are combined into one.
+type StatAggregation string
+
+const (
+	StatAggregationAvg StatAggregation = "AVG"
+	StatAggregationMin StatAggregation = "MIN"
+	StatAggregationMax StatAggregation = "MAX"
+	StatAggregationSum StatAggregation = "SUM"
+	// The most recent value in the bucket.
+	StatAggregationLast StatAggregation = "LAST"
+	// The number of values in the bucket.
+	StatAggregationCount StatAggregation = "COUNT"
+)
+
+var AllStatAggregation = []StatAggregation{
+	StatAggregationAvg,
+	StatAggregationMin,
+	StatAggregationMax,
+	StatAggregationSum,
+	StatAggregationLast,
+	StatAggregationCount,
+}
+
+func (e StatAggregation) IsValid() bool {
+	switch e {
+	case StatAggregationAvg, StatAggregationMin, StatAggregationMax, StatAggregationSum, StatAggregationLast, StatAggregationCount:
+		return true
+	}
+	return false
+}
+
+func (e StatAggregation) String() string {
+	return string(e)
+}
+
+func (e *StatAggregation) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = StatAggregation(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid StatAggregation", str)
+	}
+	return nil
+}
+
+func (e StatAggregation) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// StatBucket is the length of time each point in a stat series covers. Buckets
+// start on the hour, at midnight, on Monday, or on the first of the month, in
+// UTC.
+type StatBucket string
+
+const (
+	StatBucketHour  StatBucket = "HOUR"
+	StatBucketDay   StatBucket = "DAY"
+	StatBucketWeek  StatBucket = "WEEK"
+	StatBucketMonth StatBucket = "MONTH"
+)
+
+var AllStatBucket = []StatBucket{
+	StatBucketHour,
+	StatBucketDay,
+	StatBucketWeek,
+	StatBucketMonth,
+}
+
+func (e StatBucket) IsValid() bool {
+	switch e {
+	case StatBucketHour, StatBucketDay, StatBucketWeek, StatBucketMonth:
+		return true
+	}
+	return false
+}
+
+func (e StatBucket) String() string {
+	return string(e)
+}
+
+func (e *StatBucket) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = StatBucket(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid StatBucket", str)
+	}
+	return nil
+}
+
+func (e StatBucket) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// StatFill is what a stat series has for buckets with no values.
+type StatFill string
+
+const (
+	// Leave empty buckets out.
+	StatFillNone StatFill = "NONE"
+	// Include empty buckets with a null value.
+	StatFillNull StatFill = "NULL"
+	// Include empty buckets with a value of zero.
+	StatFillZero StatFill = "ZERO"
+	// Include empty buckets with the value of the bucket before them, or null if there isn't one.
+	StatFillPrevious StatFill = "PREVIOUS"
+)
+
+var AllStatFill = []StatFill{
+	StatFillNone,
+	StatFillNull,
+	StatFillZero,
+	StatFillPrevious,
+}
+
+func (e StatFill) IsValid() bool {
+	switch e {
+	case StatFillNone, StatFillNull, StatFillZero, StatFillPrevious:
+		return true
+	}
+	return false
+}
+
+func (e StatFill) String() string {
+	return string(e)
+}
+
+func (e *StatFill) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = StatFill(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid StatFill", str)
+	}
+	return nil
+}
+
+func (e StatFill) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+var AllWebhookDeliveryStatus = []WebhookDeliveryStatus{
+	WebhookDeliveryStatusPending,
+	WebhookDeliveryStatusDelivered,
+	WebhookDeliveryStatusFailed,
+}
+
+func (e WebhookDeliveryStatus) IsValid() bool {
+	switch e {
+	case WebhookDeliveryStatusPending, WebhookDeliveryStatusDelivered, WebhookDeliveryStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e WebhookDeliveryStatus) String() string {
+	return string(e)
+}
+
+func (e *WebhookDeliveryStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookDeliveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookDeliveryStatus", str)
+	}
+	return nil
+}
+
+func (e WebhookDeliveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// WebhookEvent is something that can be sent to a webhook. In payloads, the
+// first underscore is replaced with a dot, so post_published is sent as
+// post.published.
+type WebhookEvent string
+
+const (
+	WebhookEventPostPublished WebhookEvent = "post_published"
+	WebhookEventCommentAdded  WebhookEvent = "comment_added"
+	WebhookEventLinkUpserted  WebhookEvent = "link_upserted"
+	WebhookEventStatUpserted  WebhookEvent = "stat_upserted"
+)
+
+var AllWebhookEvent = []WebhookEvent{
+	WebhookEventPostPublished,
+	WebhookEventCommentAdded,
+	WebhookEventLinkUpserted,
+	WebhookEventStatUpserted,
+}
+
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case WebhookEventPostPublished, WebhookEventCommentAdded, WebhookEventLinkUpserted, WebhookEventStatUpserted:
+		return true
+	}
+	return false
+}
+
+func (e WebhookEvent) String() string {
+	return string(e)
+}
+
+func (e *WebhookEvent) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookEvent(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookEvent", str)
+	}
+	return nil
+}
+
+func (e WebhookEvent) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -0,0 +1,136 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	// CommentAddedChannel is the Postgres channel notified when a comment is
+	// saved for the first time.
+	CommentAddedChannel = "comment_added"
+
+	// PostPublishedChannel is the Postgres channel notified when a post
+	// becomes visible to the public.
+	PostPublishedChannel = "post_published"
+
+	// StatUpdatedChannel is the Postgres channel notified when a stat is saved.
+	StatUpdatedChannel = "stat_updated"
+)
+
+var notifications = &broker{subs: map[string]map[chan string]struct{}{}}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// notify sends a JSON payload on a Postgres channel. If e is a transaction,
+// Postgres only delivers the notification once it commits.
+func notify(ctx context.Context, e execer, channel string, payload interface{}) error {
+	blob, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal notification: %w", err)
+	}
+
+	if _, err := e.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, string(blob)); err != nil {
+		return fmt.Errorf("notify %s: %w", channel, err)
+	}
+
+	return nil
+}
+
+// ListenForNotifications listens to the Postgres channels used by
+// subscriptions and hands every notification to the subscribers in this
+// process. It stops when the context is done.
+func ListenForNotifications(ctx context.Context, dataSourceName string) error {
+	l := pq.NewListener(dataSourceName, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Errorw("postgres listener problem", "event", ev, zap.Error(err))
+		}
+	})
+
+	for _, c := range []string{CommentAddedChannel, PostPublishedChannel, StatUpdatedChannel} {
+		if err := l.Listen(c); err != nil {
+			l.Close()
+			return fmt.Errorf("listen to %s: %w", c, err)
+		}
+	}
+
+	go func() {
+		defer l.Close()
+		for {
+			select {
+			case n := <-l.Notify:
+				// A nil notification means the connection was re-established.
+				if n == nil {
+					continue
+				}
+				notifications.publish(n.Channel, n.Extra)
+			case <-time.After(90 * time.Second):
+				go func() {
+					if err := l.Ping(); err != nil {
+						log.Warnw("postgres listener ping failed", zap.Error(err))
+					}
+				}()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Subscribe returns the payloads of all notifications sent on a channel until
+// the context is done, at which point the returned channel is closed.
+func Subscribe(ctx context.Context, channel string) <-chan string {
+	return notifications.subscribe(ctx, channel)
+}
+
+type broker struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan string]struct{}
+}
+
+func (b *broker) subscribe(ctx context.Context, channel string) <-chan string {
+	ch := make(chan string, 16)
+
+	b.mu.Lock()
+	if b.subs[channel] == nil {
+		b.subs[channel] = map[chan string]struct{}{}
+	}
+	b.subs[channel][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		delete(b.subs[channel], ch)
+		close(ch)
+		b.mu.Unlock()
+	}()
+
+	return ch
+}
+
+func (b *broker) publish(channel, payload string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subs[channel] {
+		select {
+		case ch <- payload:
+		default:
+			log.Warnw("subscriber is not keeping up, dropping notification", "channel", channel)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package graphql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBroker(t *testing.T) {
+	b := &broker{subs: map[string]map[chan string]struct{}{}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch := b.subscribe(ctx, "test")
+	other := b.subscribe(ctx, "other")
+
+	b.publish("test", "hello")
+	select {
+	case got := <-ch:
+		if got != "hello" {
+			t.Errorf("expected %q, got %q", "hello", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+
+	select {
+	case got := <-other:
+		t.Errorf("did not expect a notification on other channel, got %q", got)
+	default:
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("expected channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+}
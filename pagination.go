@@ -0,0 +1,137 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxPageSize is the most items a client can ask for in one page.
+const MaxPageSize = 100
+
+// Cursor is a position in a keyset paginated list. Time is the sort key of the
+// row and ID breaks ties between rows with the same time. Lists sorted only by
+// an ID leave Time empty.
+type Cursor struct {
+	Time time.Time
+	ID   string
+}
+
+// String encodes the cursor as an opaque string for clients.
+func (c Cursor) String() string {
+	t := ""
+	if !c.Time.IsZero() {
+		t = c.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(t + "|" + c.ID))
+}
+
+// ParseCursor decodes a cursor created by Cursor.String.
+func ParseCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+
+	parts := strings.SplitN(string(b), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+
+	c := &Cursor{ID: parts[1]}
+	if parts[0] != "" {
+		c.Time, err = time.Parse(time.RFC3339Nano, parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor %q", s)
+		}
+	}
+
+	return c, nil
+}
+
+// args returns the cursor as a pair of query arguments. Both are nil if there
+// is no cursor, so queries can use "$1 IS NULL OR ..." to start at the top.
+func (c *Cursor) args() (interface{}, interface{}) {
+	if c == nil {
+		return nil, nil
+	}
+
+	return c.Time, c.ID
+}
+
+// intArgs is args for lists whose IDs are integers. It fails if the cursor's
+// ID isn't an integer, rather than letting the query fail to cast it.
+func (c *Cursor) intArgs() (interface{}, interface{}, error) {
+	if c == nil {
+		return nil, nil, nil
+	}
+
+	if _, err := strconv.ParseInt(c.ID, 10, 64); err != nil {
+		return nil, nil, fmt.Errorf("invalid cursor")
+	}
+
+	return c.Time, c.ID, nil
+}
+
+// uuidArgs is args for lists whose IDs are UUIDs. It fails if the cursor's ID
+// isn't a UUID.
+func (c *Cursor) uuidArgs() (interface{}, interface{}, error) {
+	if c == nil {
+		return nil, nil, nil
+	}
+
+	if _, err := uuid.Parse(c.ID); err != nil {
+		return nil, nil, fmt.Errorf("invalid cursor")
+	}
+
+	return c.Time, c.ID, nil
+}
+
+// ParsePage turns a page and applies defaults into a page size and a cursor.
+// The page size is at most MaxPageSize. The cursor is nil if the client
+// wants the first page.
+func ParsePage(page *Page, defaultFirst int) (int, *Cursor, error) {
+	first := defaultFirst
+	if page == nil {
+		return first, nil, nil
+	}
+
+	if page.First != nil && *page.First > 0 {
+		first = *page.First
+	}
+
+	if first > MaxPageSize {
+		first = MaxPageSize
+	}
+
+	if page.After == nil || *page.After == "" {
+		return first, nil, nil
+	}
+
+	c, err := ParseCursor(*page.After)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return first, c, nil
+}
+
+// newPageInfo builds page info for a page of count results, where the query
+// asked for one more row than first to find out if there is a next page.
+func newPageInfo(count, first int, last *Cursor) *PageInfo {
+	pi := &PageInfo{
+		HasNextPage: count > first,
+	}
+
+	if last != nil {
+		s := last.String()
+		pi.EndCursor = &s
+	}
+
+	return pi
+}
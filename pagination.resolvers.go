@@ -0,0 +1,100 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.41
+
+import (
+	"context"
+)
+
+// PostsConnection is the resolver for the postsConnection field.
+func (r *queryResolver) PostsConnection(ctx context.Context, input *Page) (*PostConnection, error) {
+	first, after, err := ParsePage(input, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	return PostsConnection(ctx, first, after)
+}
+
+// LinksConnection is the resolver for the linksConnection field.
+func (r *queryResolver) LinksConnection(ctx context.Context, input *Page) (*LinkConnection, error) {
+	first, after, err := ParsePage(input, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetLinksConnection(ctx, first, after)
+}
+
+// TweetsConnection is the resolver for the tweetsConnection field.
+func (r *queryResolver) TweetsConnection(ctx context.Context, input *Page) (*TweetConnection, error) {
+	first, after, err := ParsePage(input, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetTweetsConnection(ctx, first, after)
+}
+
+// CommentsConnection is the resolver for the commentsConnection field.
+func (r *queryResolver) CommentsConnection(ctx context.Context, input *Page) (*CommentConnection, error) {
+	first, after, err := ParsePage(input, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	return AllCommentsConnection(ctx, first, after)
+}
+
+// BooksConnection is the resolver for the booksConnection field.
+func (r *queryResolver) BooksConnection(ctx context.Context, input *Page) (*BookConnection, error) {
+	first, after, err := ParsePage(input, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetBooksConnection(ctx, first, after)
+}
+
+// StatConnection is the resolver for the statConnection field.
+func (r *queryResolver) StatConnection(ctx context.Context, key string, input *Page) (*StatConnection, error) {
+	first, after, err := ParsePage(input, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden, err := hiddenStatKeys(ctx, []string{key})
+	if err != nil {
+		return nil, err
+	}
+
+	if hidden[key] {
+		return &StatConnection{Edges: []*StatEdge{}, PageInfo: newPageInfo(0, first, nil)}, nil
+	}
+
+	return GetStatConnection(ctx, key, first, after)
+}
+
+// LogsConnection is the resolver for the logsConnection field.
+func (r *queryResolver) LogsConnection(ctx context.Context, input *Page) (*LogConnection, error) {
+	u := GetUserFromContext(ctx)
+	first, after, err := ParsePage(input, 25)
+	if err != nil {
+		return nil, err
+	}
+
+	return UserLogsConnection(ctx, u, first, after)
+}
+
+// PhotosConnection is the resolver for the photosConnection field.
+func (r *queryResolver) PhotosConnection(ctx context.Context, input *Page) (*PhotoConnection, error) {
+	u := GetUserFromContext(ctx)
+	first, after, err := ParsePage(input, 25)
+	if err != nil {
+		return nil, err
+	}
+
+	return UserPhotosConnection(ctx, u, first, after)
+}
@@ -0,0 +1,82 @@
+package graphql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	tests := map[string]Cursor{
+		"time and id": {Time: time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC), ID: "42"},
+		"id only":     {ID: "1234"},
+		"uuid":        {Time: time.Now().UTC(), ID: "9f1c2a4e-0b5d-4f6a-8c3e-2d7b9a1e5f00"},
+		"pipe in id":  {Time: time.Now().UTC(), ID: "a|b"},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseCursor(tc.String())
+			if err != nil {
+				t.Fatalf("ParseCursor(%q): %v", tc.String(), err)
+			}
+
+			if got.ID != tc.ID || !got.Time.Equal(tc.Time) {
+				t.Errorf("expected %+v, got %+v", tc, got)
+			}
+		})
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	first := 5
+	bad := "not a cursor!"
+	if _, _, err := ParsePage(&Page{After: &bad}, 10); err == nil {
+		t.Error("expected error for invalid cursor")
+	}
+
+	after := Cursor{ID: "7"}.String()
+	n, c, err := ParsePage(&Page{First: &first, After: &after}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 5 || c == nil || c.ID != "7" {
+		t.Errorf("unexpected page %d %+v", n, c)
+	}
+
+	if n, c, _ := ParsePage(nil, 10); n != 10 || c != nil {
+		t.Errorf("unexpected default page %d %+v", n, c)
+	}
+}
+
+func TestParsePageMax(t *testing.T) {
+	first := 1000000
+	if n, _, err := ParsePage(&Page{First: &first}, 10); err != nil || n != MaxPageSize {
+		t.Errorf("ParsePage(first: %d) = %d, %v, want %d", first, n, err, MaxPageSize)
+	}
+}
+
+func TestCursorIDArgs(t *testing.T) {
+	var none *Cursor
+	if _, id, err := none.intArgs(); err != nil || id != nil {
+		t.Errorf("nil cursor intArgs() = %v, %v, want nil, nil", id, err)
+	}
+
+	if _, _, err := (&Cursor{ID: "42"}).intArgs(); err != nil {
+		t.Errorf("intArgs(42) = %v, want nil", err)
+	}
+
+	if _, _, err := (&Cursor{ID: "abc"}).intArgs(); err == nil || err.Error() != "invalid cursor" {
+		t.Errorf("intArgs(abc) = %v, want invalid cursor", err)
+	}
+
+	if _, _, err := (&Cursor{ID: "9f1c2a4e-0b5d-4f6a-8c3e-2d7b9a1e5f00"}).uuidArgs(); err != nil {
+		t.Errorf("uuidArgs(uuid) = %v, want nil", err)
+	}
+
+	if _, _, err := (&Cursor{ID: "42"}).uuidArgs(); err == nil || err.Error() != "invalid cursor" {
+		t.Errorf("uuidArgs(42) = %v, want invalid cursor", err)
+	}
+}
@@ -74,6 +74,16 @@ func (p *Photo) Upload(ctx context.Context, f io.Reader) error {
 	return nil
 }
 
+// DeleteFile removes the photo's file from GCS.
+func (p *Photo) DeleteFile(ctx context.Context) error {
+	stgClient, err := configureStorage(ctx, StorageBucketName)
+	if err != nil {
+		return err
+	}
+
+	return stgClient.Object(p.Path()).Delete(ctx)
+}
+
 // Save adds the photo to the database and checks that no data is missing.
 func (p *Photo) Save(ctx context.Context) error {
 	if p.ID == "" {
@@ -180,3 +190,63 @@ func UserPhotos(ctx context.Context, u *User, limit int, offset int) ([]*Photo,
 	}
 	return photos, nil
 }
+
+// UserPhotosConnection gets a page of photos for a User, newest first,
+// starting after the cursor.
+func UserPhotosConnection(ctx context.Context, u *User, first int, after *Cursor) (*PhotoConnection, error) {
+	if u == nil {
+		return nil, fmt.Errorf("no user specified")
+	}
+
+	t, id := after.args()
+	rows, err := db.QueryContext(
+		ctx, `
+    SELECT id, year, content_type, user_id, created_at, modified_at
+    FROM photos
+    WHERE user_id = $1
+      AND ($2::timestamptz IS NULL OR (created_at, id) < ($2::timestamptz, $3::text))
+    ORDER BY created_at DESC, id DESC
+    LIMIT $4
+    `,
+		u.ID, t, id, first+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conn := &PhotoConnection{Edges: []*PhotoEdge{}}
+	var last *Cursor
+	count := 0
+	for rows.Next() {
+		p := &Photo{}
+		if err := rows.Scan(
+			&p.ID,
+			&p.Year,
+			&p.ContentType,
+			&p.User.ID,
+			&p.Created,
+			&p.Modified,
+		); err != nil {
+			return nil, err
+		}
+
+		count++
+		if count > first {
+			break
+		}
+
+		last = &Cursor{Time: p.Created, ID: p.ID}
+		conn.Edges = append(conn.Edges, &PhotoEdge{Cursor: last.String(), Node: p})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	conn.PageInfo = newPageInfo(count, first, last)
+
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM photos WHERE user_id = $1", u.ID).Scan(&conn.TotalCount); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
@@ -25,7 +25,7 @@ type Post struct {
 	Modified time.Time `json:"modified"`
 	Draft    bool      `json:"draft"`
 	Tags     []string  `json:"tags"`
-	Links    []*Link   `json:"links"`
+	Slug     string    `json:"slug"`
 }
 
 // GetMaxID returns the greatest post ID in the database.
@@ -39,7 +39,8 @@ func GetMaxID(ctx context.Context) (int64, error) {
 	return id, nil
 }
 
-// GetPostString gets a post by an ID string.
+// GetPostString gets a post by an ID string. If the string is not a number,
+// it is looked up as a slug.
 func GetPostString(ctx context.Context, id string) (*Post, error) {
 	match, err := regexp.MatchString("^[0-9]+$", id)
 	if err != nil {
@@ -47,7 +48,16 @@ func GetPostString(ctx context.Context, id string) (*Post, error) {
 	}
 
 	if !match {
-		return nil, fmt.Errorf("no post with id %s", id)
+		p, err := GetPostBySlug(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if p == nil {
+			return nil, fmt.Errorf("no post with id %s", id)
+		}
+
+		return p, nil
 	}
 
 	i, err := strconv.ParseInt(id, 10, 64)
@@ -61,8 +71,8 @@ func GetPostString(ctx context.Context, id string) (*Post, error) {
 // GetPost gets a post by ID from the database.
 func GetPost(ctx context.Context, id int64) (*Post, error) {
 	var post Post
-	row := db.QueryRowContext(ctx, "SELECT id, title, content, date, created_at, modified_at, tags, draft FROM posts WHERE id = $1", id)
-	err := row.Scan(&post.ID, &post.Title, &post.Content, &post.Datetime, &post.Created, &post.Modified, pq.Array(&post.Tags), &post.Draft)
+	row := db.QueryRowContext(ctx, "SELECT id, title, content, date, created_at, modified_at, tags, draft, slug FROM posts WHERE id = $1", id)
+	err := row.Scan(&post.ID, &post.Title, &post.Content, &post.Datetime, &post.Created, &post.Modified, pq.Array(&post.Tags), &post.Draft, &post.Slug)
 	switch {
 	case err == sql.ErrNoRows:
 		return nil, nil
@@ -102,7 +112,7 @@ func AllTags(ctx context.Context) ([]string, error) {
 // Drafts is a simple wrapper around Posts that does return drafts.
 func Drafts(ctx context.Context, limit, offset int) ([]*Post, error) {
 	query := `
-SELECT id, title, content, date, created_at, modified_at, tags, draft
+SELECT id, title, content, date, created_at, modified_at, tags, draft, slug
 FROM posts
 WHERE draft = true
 ORDER BY date DESC
@@ -111,10 +121,6 @@ LIMIT $1 OFFSET $2
 	return postQuery(ctx, query, limit, offset)
 }
 
-var tagAliases = map[string]string{
-	"hackerschool": "recursecenter",
-}
-
 // ParseTags returns a list of all hashtags currently in a post.
 func ParseTags(text string) ([]string, error) {
 	// http://golang.org/pkg/regexp/#Regexp.FindAllStringSubmatch
@@ -123,10 +129,6 @@ func ParseTags(text string) ([]string, error) {
 	for _, v := range finds {
 		if len(v) > 2 {
 			tag := strings.ToLower(v[2])
-
-			if alias, ok := tagAliases[tag]; ok {
-				tagMap[alias]++
-			}
 			tagMap[tag]++
 		}
 	}
@@ -156,7 +158,11 @@ func (p *Post) Save(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	p.Tags = tags
+
+	p.Tags, err = CanonicalTags(ctx, db, tags)
+	if err != nil {
+		return err
+	}
 
 	if p.Title == "" {
 		p.Title = fmt.Sprintf("Untitled #%s", p.ID)
@@ -172,13 +178,35 @@ func (p *Post) Save(ctx context.Context) error {
 
 	p.Modified = time.Now()
 
-	if _, err := db.ExecContext(
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	wasPublished := false
+	var oldDraft bool
+	var oldDate time.Time
+	row := tx.QueryRowContext(ctx, "SELECT draft, date FROM posts WHERE id = $1", p.ID)
+	switch err := row.Scan(&oldDraft, &oldDate); {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return err
+	default:
+		wasPublished = !oldDraft && !oldDate.After(time.Now())
+	}
+
+	if err := saveSlug(ctx, tx, p); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(
 		ctx,
 		`
-INSERT INTO posts(id, title, content, date, draft, created_at, modified_at, tags)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO posts(id, title, content, date, draft, created_at, modified_at, tags, slug)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 ON CONFLICT (id) DO UPDATE
-SET (title, content, date, draft, created_at, modified_at, tags) = ($2, $3, $4, $5, $6, $7, $8)
+SET (title, content, date, draft, created_at, modified_at, tags, slug) = ($2, $3, $4, $5, $6, $7, $8, $9)
 WHERE posts.id = $1;
 `,
 		p.ID,
@@ -188,7 +216,24 @@ WHERE posts.id = $1;
 		p.Draft,
 		p.Created,
 		p.Modified,
-		pq.Array(p.Tags)); err != nil {
+		pq.Array(p.Tags),
+		p.Slug); err != nil {
+		return err
+	}
+
+	if err := savePostLinks(ctx, tx, p); err != nil {
+		return err
+	}
+
+	if err := saveRevision(ctx, tx, p); err != nil {
+		return err
+	}
+
+	if err := recordPublication(ctx, tx, p, wasPublished); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
 		return err
 	}
 
@@ -200,8 +245,14 @@ WHERE posts.id = $1;
 	return nil
 }
 
-// Comments returns the comments for a post
-func (p *Post) Comments(ctx context.Context, input *Limit) ([]*Comment, error) {
+// IsPublished returns true if the post is visible to the public.
+func (p *Post) IsPublished() bool {
+	return !p.Draft && !p.Datetime.After(time.Now())
+}
+
+// Comments returns the comments for a post, oldest first. If threaded is true,
+// replies follow the comment they reply to.
+func (p *Post) Comments(ctx context.Context, input *Limit, threaded *bool) ([]*Comment, error) {
 	limit := 100
 	offset := 0
 	if input != nil {
@@ -215,6 +266,10 @@ func (p *Post) Comments(ctx context.Context, input *Limit) ([]*Comment, error) {
 		}
 	}
 
+	if threaded != nil && *threaded {
+		return PostCommentThreads(ctx, p.ID, limit, offset)
+	}
+
 	return PostComments(ctx, p.ID, limit, offset)
 }
 
@@ -242,8 +297,13 @@ func (p *Post) HTML() template.HTML {
 	return Markdown(p.Content)
 }
 
-// URI returns an absolute link to this post.
+// URI returns an absolute link to this post. Posts without a slug are linked
+// by ID.
 func (p *Post) URI() *URI {
+	if p.Slug != "" {
+		return NewURI(fmt.Sprintf("https://writing.natwelch.com/post/%s", p.Slug))
+	}
+
 	return NewURI(fmt.Sprintf("https://writing.natwelch.com/post/%s", p.ID))
 }
 
@@ -294,6 +354,14 @@ func (p *Post) Readtime(_ context.Context) int32 {
 // graphql.
 func (p *Post) IsLinkable() {}
 
+// IsSearchable exists to show that this method implements the Searchable
+// type in graphql.
+func (p *Post) IsSearchable() {}
+
+func (p *Post) GetSummary() string {
+	return p.Summary()
+}
+
 // Related returns an array of related posts. It is quite slow in comparison to
 // other queries.
 func (p *Post) Related(ctx context.Context, input *Limit) ([]*Post, error) {
@@ -365,7 +433,7 @@ func (p *Post) Related(ctx context.Context, input *Limit) ([]*Post, error) {
 
 // GetRandomPosts returns a random selection of posts.
 func GetRandomPosts(ctx context.Context, limit int, notIn []int64) ([]*Post, error) {
-	query := `SELECT id, title, content, date, created_at, modified_at, tags, draft
+	query := `SELECT id, title, content, date, created_at, modified_at, tags, draft, slug
   FROM posts
   WHERE draft = false
     AND id <> ALL($1)
@@ -377,7 +445,7 @@ func GetRandomPosts(ctx context.Context, limit int, notIn []int64) ([]*Post, err
 // Posts returns some posts.
 func Posts(ctx context.Context, limit int, offset int) ([]*Post, error) {
 	query := `
-SELECT id, title, content, date, created_at, modified_at, tags, draft
+SELECT id, title, content, date, created_at, modified_at, tags, draft, slug
 FROM posts
 WHERE draft = false
   AND date <= NOW()
@@ -390,7 +458,7 @@ LIMIT $1 OFFSET $2
 // FuturePosts returns some posts that are in the future.
 func FuturePosts(ctx context.Context, limit int, offset int) ([]*Post, error) {
 	query := `
-SELECT id, title, content, date, created_at, modified_at, tags, draft
+SELECT id, title, content, date, created_at, modified_at, tags, draft, slug
 FROM posts
 WHERE draft = false
   AND date > NOW()
@@ -400,17 +468,23 @@ LIMIT $1 OFFSET $2
 	return postQuery(ctx, query, limit, offset)
 }
 
-// PostsByTag returns all posts with a tag.
-func PostsByTag(ctx context.Context, tag string) ([]*Post, error) {
+// MaxTagPosts is the most posts postsByTag returns.
+const MaxTagPosts = 1000
+
+// PostsByTag returns the newest published posts with a tag, or with the tag
+// an alias belongs to.
+func PostsByTag(ctx context.Context, tag string, limit int) ([]*Post, error) {
 	query := `
-SELECT id, title, content, date, created_at, modified_at, tags, draft
+SELECT id, title, content, date, created_at, modified_at, tags, draft, slug
 FROM posts
-WHERE $1 = ANY(tags)
+WHERE tags @> ARRAY[COALESCE((SELECT tag FROM tag_aliases WHERE alias = $1), $1)]
   AND draft = false
+  AND date <= NOW()
 ORDER BY date DESC
+LIMIT $2
 `
 
-	return postQuery(ctx, query, tag)
+	return postQuery(ctx, query, tag, limit)
 }
 
 func postQuery(ctx context.Context, query string, args ...interface{}) ([]*Post, error) {
@@ -423,7 +497,7 @@ func postQuery(ctx context.Context, query string, args ...interface{}) ([]*Post,
 	posts := make([]*Post, 0)
 	for rows.Next() {
 		post := new(Post)
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Datetime, &post.Created, &post.Modified, pq.Array(&post.Tags), &post.Draft)
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Datetime, &post.Created, &post.Modified, pq.Array(&post.Tags), &post.Draft, &post.Slug)
 		if err != nil {
 			return nil, err
 		}
@@ -436,3 +510,45 @@ func postQuery(ctx context.Context, query string, args ...interface{}) ([]*Post,
 	return posts, nil
 
 }
+
+// PostsConnection returns a page of published posts, newest first, starting
+// after the cursor.
+func PostsConnection(ctx context.Context, first int, after *Cursor) (*PostConnection, error) {
+	t, id, err := after.intArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+SELECT id, title, content, date, created_at, modified_at, tags, draft, slug
+FROM posts
+WHERE draft = false
+  AND date <= NOW()
+  AND ($1::timestamptz IS NULL OR (date, id) < ($1::timestamptz, $2::bigint))
+ORDER BY date DESC, id DESC
+LIMIT $3
+`
+	posts, err := postQuery(ctx, query, t, id, first+1)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &PostConnection{Edges: []*PostEdge{}}
+	var last *Cursor
+	for i, p := range posts {
+		if i == first {
+			break
+		}
+
+		last = &Cursor{Time: p.Datetime, ID: p.ID}
+		conn.Edges = append(conn.Edges, &PostEdge{Cursor: last.String(), Node: p})
+	}
+	conn.PageInfo = newPageInfo(len(posts), first, last)
+
+	row := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts WHERE draft = false AND date <= NOW()")
+	if err := row.Scan(&conn.TotalCount); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,219 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/russross/blackfriday/v2"
+)
+
+// OutboundLink is a link found in the Markdown of a post.
+type OutboundLink struct {
+	URI   string
+	Title string
+}
+
+// ExtractLinks returns the absolute http and https links in a chunk of
+// Markdown, in the order they first appear.
+func ExtractLinks(content string) []OutboundLink {
+	md := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions))
+	root := md.Parse([]byte(content))
+
+	seen := map[string]bool{}
+	links := []OutboundLink{}
+	root.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || node.Type != blackfriday.Link {
+			return blackfriday.GoToNext
+		}
+
+		dest := strings.TrimSpace(string(node.LinkData.Destination))
+		u, err := url.Parse(dest)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return blackfriday.GoToNext
+		}
+
+		if seen[dest] {
+			return blackfriday.GoToNext
+		}
+		seen[dest] = true
+
+		links = append(links, OutboundLink{URI: dest, Title: linkText(node)})
+
+		return blackfriday.GoToNext
+	})
+
+	return links
+}
+
+// linkText returns the plain text inside a link node.
+func linkText(link *blackfriday.Node) string {
+	var b strings.Builder
+	link.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && (node.Type == blackfriday.Text || node.Type == blackfriday.Code) {
+			b.Write(node.Literal)
+		}
+		return blackfriday.GoToNext
+	})
+
+	return strings.TrimSpace(b.String())
+}
+
+// savePostLinks records the links in a post, creating a link for each URI we
+// have not seen before.
+func savePostLinks(ctx context.Context, tx *sql.Tx, p *Post) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM post_links WHERE post_id = $1", p.ID); err != nil {
+		return fmt.Errorf("clear post links: %w", err)
+	}
+
+	for i, l := range ExtractLinks(p.Content) {
+		title := l.Title
+		if title == "" {
+			title = l.URI
+		}
+
+		var id string
+		row := tx.QueryRowContext(ctx, `
+WITH inserted AS (
+  INSERT INTO links(title, uri, description, created, created_at, modified_at, tags)
+  VALUES ($1, $2, '', NOW(), NOW(), NOW(), '{}')
+  ON CONFLICT (uri) DO NOTHING
+  RETURNING id
+)
+SELECT id FROM inserted
+UNION ALL
+SELECT id FROM links WHERE uri = $2
+LIMIT 1
+`, title, l.URI)
+		if err := row.Scan(&id); err != nil {
+			return fmt.Errorf("save link %q: %w", l.URI, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO post_links(post_id, link_id, position)
+VALUES ($1, $2, $3)
+ON CONFLICT (post_id, link_id) DO NOTHING
+`, p.ID, id, i); err != nil {
+			return fmt.Errorf("save post link: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE posts SET links_saved = true WHERE id = $1", p.ID); err != nil {
+		return fmt.Errorf("mark post links saved: %w", err)
+	}
+
+	return nil
+}
+
+// postLinkBackfillBatch is how many posts BackfillPostLinks saves the links
+// of in one transaction.
+const postLinkBackfillBatch = 100
+
+// BackfillPostLinks saves the links of every post that hasn't had them saved,
+// such as posts written before links were tracked. It doesn't send
+// webmentions. It returns the number of posts updated.
+func BackfillPostLinks(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := backfillPostLinks(ctx, postLinkBackfillBatch)
+		total += n
+		if err != nil || n < postLinkBackfillBatch {
+			return total, err
+		}
+	}
+}
+
+func backfillPostLinks(ctx context.Context, limit int) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+SELECT id, content
+FROM posts
+WHERE NOT links_saved
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("get posts without links: %w", err)
+	}
+
+	var posts []*Post
+	for rows.Next() {
+		p := new(Post)
+		if err := rows.Scan(&p.ID, &p.Content); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		posts = append(posts, p)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range posts {
+		if err := savePostLinks(ctx, tx, p); err != nil {
+			return 0, fmt.Errorf("post %s: %w", p.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(posts), nil
+}
+
+// Links returns the links referenced in a post, in the order they appear.
+func (p *Post) Links(ctx context.Context) ([]*Link, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT links.id, links.title, links.uri, links.description, links.created, links.modified_at, links.tags
+FROM post_links
+JOIN links ON links.id = post_links.link_id
+WHERE post_links.post_id = $1
+ORDER BY post_links.position
+`, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]*Link, 0)
+	for rows.Next() {
+		link := new(Link)
+		if err := rows.Scan(&link.ID, &link.Title, &link.URI, &link.Description, &link.Created, &link.Modified, pq.Array(&link.Tags)); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// Posts returns the published posts that reference this link, newest first.
+func (l *Link) Posts(ctx context.Context, input *Limit) ([]*Post, error) {
+	limit, offset := ParseLimit(input, 10, 0)
+
+	return postQuery(ctx, `
+SELECT id, title, content, date, created_at, modified_at, tags, draft, slug
+FROM posts
+WHERE id IN (SELECT post_id FROM post_links WHERE link_id = $1)
+  AND draft = false
+  AND date <= NOW()
+ORDER BY date DESC
+LIMIT $2 OFFSET $3
+`, l.ID, limit, offset)
+}
@@ -0,0 +1,81 @@
+package graphql
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExtractLinks(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		want    []OutboundLink
+	}{
+		"inline": {
+			content: "Read [the *docs*](https://example.com/docs) today.",
+			want:    []OutboundLink{{URI: "https://example.com/docs", Title: "the docs"}},
+		},
+		"autolink": {
+			content: "See https://example.com for more.",
+			want:    []OutboundLink{{URI: "https://example.com", Title: "https://example.com"}},
+		},
+		"duplicates and relative": {
+			content: "[a](http://a.com) [tag](/tags/go) [again](http://a.com) [mail](mailto:me@a.com)",
+			want:    []OutboundLink{{URI: "http://a.com", Title: "a"}},
+		},
+		"hashtag": {
+			content: "Nothing to see #here",
+			want:    []OutboundLink{},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := ExtractLinks(tc.content); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ExtractLinks(%q) = %+v, want %+v", tc.content, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBackfillPostLinks(t *testing.T) {
+	m := mockDB(t)
+
+	m.ExpectBegin()
+	m.ExpectQuery(`(?s)SELECT id, content\s+FROM posts\s+WHERE NOT links_saved`).
+		WithArgs(postLinkBackfillBatch).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content"}).
+			AddRow("1", "Read [the docs](https://example.com/docs).").
+			AddRow("2", "No links here."))
+
+	m.ExpectExec(regexp.QuoteMeta("DELETE FROM post_links WHERE post_id = $1")).
+		WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery(regexp.QuoteMeta("INSERT INTO links(title, uri")).
+		WithArgs("the docs", "https://example.com/docs").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("0b6f4a3e-8c59-4a4e-9d3a-1b2c3d4e5f60"))
+	m.ExpectExec(regexp.QuoteMeta("INSERT INTO post_links(post_id, link_id, position)")).
+		WithArgs("1", "0b6f4a3e-8c59-4a4e-9d3a-1b2c3d4e5f60", 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	m.ExpectExec(regexp.QuoteMeta("UPDATE posts SET links_saved = true WHERE id = $1")).
+		WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	m.ExpectExec(regexp.QuoteMeta("DELETE FROM post_links WHERE post_id = $1")).
+		WithArgs("2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectExec(regexp.QuoteMeta("UPDATE posts SET links_saved = true WHERE id = $1")).
+		WithArgs("2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	m.ExpectCommit()
+
+	n, err := BackfillPostLinks(context.Background())
+	if err != nil || n != 2 {
+		t.Errorf("BackfillPostLinks() = %d, %v, want 2, nil", n, err)
+	}
+}
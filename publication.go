@@ -0,0 +1,174 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Publication records when a post became visible to the public. Upcoming
+// publications have not happened yet, so they have no Published time.
+type Publication struct {
+	PostID    string     `json:"post_id"`
+	Scheduled time.Time  `json:"scheduled"`
+	Published *time.Time `json:"published"`
+}
+
+// Post returns the post being published.
+func (p *Publication) Post(ctx context.Context) (*Post, error) {
+	return GetPostString(ctx, p.PostID)
+}
+
+// recordPublication is called when saving a post changes whether it is
+// public. Posts that are published get a publication that still needs
+// processing. Posts that are hidden again lose theirs, so they are published
+// again when they come back.
+func recordPublication(ctx context.Context, tx *sql.Tx, p *Post, wasPublished bool) error {
+	switch {
+	case !wasPublished && p.IsPublished():
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO post_publications(post_id, published_at)
+VALUES ($1, $2)
+ON CONFLICT (post_id) DO UPDATE
+SET (published_at, processed_at) = ($2, NULL)
+WHERE post_publications.post_id = $1;
+`, p.ID, time.Now()); err != nil {
+			return fmt.Errorf("record publication: %w", err)
+		}
+
+		if err := notify(ctx, tx, PostPublishedChannel, map[string]string{"id": p.ID}); err != nil {
+			return err
+		}
+
+		return enqueueEvent(ctx, tx, WebhookEventPostPublished, publishedPostData(p))
+	case wasPublished && !p.IsPublished():
+		if _, err := tx.ExecContext(ctx, "DELETE FROM post_publications WHERE post_id = $1", p.ID); err != nil {
+			return fmt.Errorf("remove publication: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RecordDuePublications finds posts that were scheduled for the future and
+// whose time has now come, records their publication and notifies
+// subscribers and webhooks. It returns the number of posts published. If
+// another server records a post at the same time, only the one that inserted
+// it sends the notifications.
+func RecordDuePublications(ctx context.Context) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+INSERT INTO post_publications(post_id, published_at)
+SELECT id, date
+FROM posts
+WHERE draft = false
+  AND date <= NOW()
+  AND NOT EXISTS (SELECT 1 FROM post_publications WHERE post_id = posts.id)
+ON CONFLICT (post_id) DO NOTHING
+RETURNING post_id
+`)
+	if err != nil {
+		return 0, fmt.Errorf("record due publications: %w", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := notify(ctx, tx, PostPublishedChannel, map[string]string{"id": id}); err != nil {
+			return 0, err
+		}
+
+		p, err := GetPostString(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+
+		if p == nil {
+			continue
+		}
+
+		if err := enqueueEvent(ctx, tx, WebhookEventPostPublished, publishedPostData(p)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+// ClaimPublications marks every unprocessed publication as processed and
+// returns them. Claiming and processing in one statement means only one
+// server handles each publication, even with many servers running. A
+// publication's scheduled time is its post's date, or when it was published
+// if the post has since been deleted.
+func ClaimPublications(ctx context.Context) ([]*Publication, error) {
+	return publicationQuery(ctx, `
+WITH claimed AS (
+  UPDATE post_publications
+  SET processed_at = NOW()
+  WHERE processed_at IS NULL
+  RETURNING post_id, published_at
+)
+SELECT claimed.post_id, COALESCE(posts.date, claimed.published_at), claimed.published_at
+FROM claimed
+LEFT JOIN posts ON posts.id = claimed.post_id
+`)
+}
+
+// ScheduledPublications returns posts that will be published in the future,
+// soonest first.
+func ScheduledPublications(ctx context.Context, limit, offset int) ([]*Publication, error) {
+	return publicationQuery(ctx, `
+SELECT id, date, NULL::timestamptz
+FROM posts
+WHERE draft = false
+  AND date > NOW()
+ORDER BY date ASC
+LIMIT $1 OFFSET $2
+`, limit, offset)
+}
+
+func publicationQuery(ctx context.Context, query string, args ...interface{}) ([]*Publication, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pubs := make([]*Publication, 0)
+	for rows.Next() {
+		p := new(Publication)
+		if err := rows.Scan(&p.PostID, &p.Scheduled, &p.Published); err != nil {
+			return nil, err
+		}
+		pubs = append(pubs, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pubs, nil
+}
@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// mockDB swaps the package database for a mock until the test ends. Tests
+// that use it can't run in parallel.
+func mockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+
+	mock, m, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := db
+	db = mock
+	t.Cleanup(func() {
+		db = old
+		mock.Close()
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return m
+}
+
+func TestClaimPublications(t *testing.T) {
+	m := mockDB(t)
+
+	scheduled := time.Date(2023, 10, 1, 9, 0, 0, 0, time.UTC)
+	published := time.Date(2023, 10, 1, 9, 0, 30, 0, time.UTC)
+	m.ExpectQuery(regexp.QuoteMeta("SET processed_at = NOW()")).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "date", "published_at"}).
+			AddRow("7", scheduled, published))
+
+	pubs, err := ClaimPublications(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pubs) != 1 {
+		t.Fatalf("expected 1 publication, got %d", len(pubs))
+	}
+
+	p := pubs[0]
+	if p.PostID != "7" || !p.Scheduled.Equal(scheduled) || p.Published == nil || !p.Published.Equal(published) {
+		t.Errorf("unexpected publication %+v", p)
+	}
+}
+
+func TestClaimPublicationsUsesPostDate(t *testing.T) {
+	m := mockDB(t)
+
+	// The scheduled time has to come from the post, not the publication.
+	m.ExpectQuery(`(?s)RETURNING post_id, published_at\s*\).*COALESCE\(posts\.date, claimed\.published_at\).*LEFT JOIN posts`).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "date", "published_at"}))
+
+	pubs, err := ClaimPublications(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pubs) != 0 {
+		t.Errorf("expected no publications, got %d", len(pubs))
+	}
+}
+
+func TestRecordDuePublicationsSkipsConflicts(t *testing.T) {
+	m := mockDB(t)
+
+	m.ExpectBegin()
+	m.ExpectQuery(`(?s)INSERT INTO post_publications.*ON CONFLICT \(post_id\) DO NOTHING\s+RETURNING post_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id"}))
+	m.ExpectCommit()
+
+	n, err := RecordDuePublications(context.Background())
+	if err != nil || n != 0 {
+		t.Errorf("RecordDuePublications() = %d, %v, want 0, nil", n, err)
+	}
+}
@@ -10,7 +10,10 @@ import (
 type key int8
 
 const (
-	userCtxKey key = 0
+	userCtxKey      key = 0
+	loadersCtxKey   key = 1
+	rateLimitCtxKey key = 2
+	apiKeyCtxKey    key = 3
 )
 
 // GetUserFromContext finds the user from the context. This is usually inserted
@@ -65,6 +68,10 @@ func New() Config {
 			return nil, fmt.Errorf("forbidden")
 		}
 
+		if err := checkFieldScoped(ctx); err != nil {
+			return nil, err
+		}
+
 		// or let it pass through
 		return next(ctx)
 	}
@@ -76,9 +83,21 @@ func New() Config {
 			return nil, fmt.Errorf("forbidden")
 		}
 
+		if err := checkFieldScoped(ctx); err != nil {
+			return nil, err
+		}
+
 		// or let it pass through
 		return next(ctx)
 	}
 
+	c.Directives.HasScope = func(ctx context.Context, _ interface{}, next graphql.Resolver, scope Scope) (interface{}, error) {
+		if err := CheckScope(ctx, scope); err != nil {
+			return nil, err
+		}
+
+		return next(ctx)
+	}
+
 	return c
 }
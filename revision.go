@@ -0,0 +1,208 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// PostRevision is a snapshot of a post's title and content, written every
+// time the post is saved.
+type PostRevision struct {
+	ID      string    `json:"id"`
+	PostID  string    `json:"post_id"`
+	UserID  string    `json:"user_id"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	Created time.Time `json:"created"`
+}
+
+// Post returns the post this revision belongs to.
+func (r *PostRevision) Post(ctx context.Context) (*Post, error) {
+	return GetPostString(ctx, r.PostID)
+}
+
+// User returns the user who saved this revision. Revisions saved outside of a
+// request have no user.
+func (r *PostRevision) User(ctx context.Context) (*User, error) {
+	if r.UserID == "" {
+		return nil, nil
+	}
+
+	return LoadUser(ctx, r.UserID)
+}
+
+// Diff returns a unified diff from another revision to this one. If against
+// is nil, the diff is from the revision saved before this one.
+func (r *PostRevision) Diff(ctx context.Context, against *string) (string, error) {
+	var from *PostRevision
+	var err error
+	if against != nil {
+		from, err = GetPostRevision(ctx, *against)
+	} else {
+		from, err = r.previous(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return DiffRevisions(from, r)
+}
+
+func (r *PostRevision) previous(ctx context.Context) (*PostRevision, error) {
+	revs, err := postRevisionQuery(ctx, `
+SELECT id, post_id, COALESCE(user_id, ''), title, content, created_at
+FROM post_revisions
+WHERE post_id = $1
+  AND id < $2
+ORDER BY id DESC
+LIMIT 1
+`, r.PostID, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(revs) == 0 {
+		return nil, nil
+	}
+
+	return revs[0], nil
+}
+
+// DiffRevisions returns a unified diff of the title and content of two
+// revisions of the same post. A nil from is treated as an empty post.
+func DiffRevisions(from, to *PostRevision) (string, error) {
+	if to == nil {
+		return "", fmt.Errorf("no revision to diff")
+	}
+
+	if from != nil && from.PostID != to.PostID {
+		return "", fmt.Errorf("revision %s is not of the same post as revision %s", from.ID, to.ID)
+	}
+
+	if from == nil {
+		from = &PostRevision{}
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(revisionText(from)),
+		B:        difflib.SplitLines(revisionText(to)),
+		FromFile: fmt.Sprintf("revision/%s", from.ID),
+		FromDate: from.Created.Format(time.RFC3339),
+		ToFile:   fmt.Sprintf("revision/%s", to.ID),
+		ToDate:   to.Created.Format(time.RFC3339),
+		Context:  3,
+	})
+}
+
+func revisionText(r *PostRevision) string {
+	if r.ID == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("# %s\n\n%s\n", r.Title, r.Content)
+}
+
+// saveRevision writes the current state of a post as a new revision.
+func saveRevision(ctx context.Context, tx *sql.Tx, p *Post) error {
+	var userID *string
+	if u := GetUserFromContext(ctx); u != nil {
+		userID = &u.ID
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`
+INSERT INTO post_revisions(post_id, user_id, title, content, created_at)
+VALUES ($1, $2, $3, $4, $5);
+`,
+		p.ID,
+		userID,
+		p.Title,
+		p.Content,
+		p.Modified); err != nil {
+		return fmt.Errorf("save revision: %w", err)
+	}
+
+	return nil
+}
+
+// GetPostRevision gets a single revision by ID.
+func GetPostRevision(ctx context.Context, id string) (*PostRevision, error) {
+	var r PostRevision
+	row := db.QueryRowContext(ctx, "SELECT id, post_id, COALESCE(user_id, ''), title, content, created_at FROM post_revisions WHERE id = $1", id)
+	err := row.Scan(&r.ID, &r.PostID, &r.UserID, &r.Title, &r.Content, &r.Created)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, fmt.Errorf("no revision %q", id)
+	case err != nil:
+		return nil, fmt.Errorf("error with get: %w", err)
+	default:
+		return &r, nil
+	}
+}
+
+// Revisions returns the saved revisions of a post, newest first.
+func (p *Post) Revisions(ctx context.Context, input *Limit) ([]*PostRevision, error) {
+	limit, offset := ParseLimit(input, 10, 0)
+
+	return postRevisionQuery(ctx, `
+SELECT id, post_id, COALESCE(user_id, ''), title, content, created_at
+FROM post_revisions
+WHERE post_id = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3
+`, p.ID, limit, offset)
+}
+
+// RestorePostRevision sets a post's title and content back to what they were
+// in a revision. The restore is saved as a new revision, so it can be undone.
+func RestorePostRevision(ctx context.Context, id string) (*Post, error) {
+	r, err := GetPostRevision(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := GetPostString(ctx, r.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	if p == nil {
+		return nil, fmt.Errorf("post %s no longer exists", r.PostID)
+	}
+
+	p.Title = r.Title
+	p.Content = r.Content
+	if err := p.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return GetPostString(ctx, p.ID)
+}
+
+func postRevisionQuery(ctx context.Context, query string, args ...interface{}) ([]*PostRevision, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revs := make([]*PostRevision, 0)
+	for rows.Next() {
+		r := new(PostRevision)
+		if err := rows.Scan(&r.ID, &r.PostID, &r.UserID, &r.Title, &r.Content, &r.Created); err != nil {
+			return nil, err
+		}
+		revs = append(revs, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revs, nil
+}
@@ -0,0 +1,40 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	from := &PostRevision{ID: "1", PostID: "7", Title: "Hello", Content: "one\ntwo\nthree"}
+	to := &PostRevision{ID: "2", PostID: "7", Title: "Hello", Content: "one\n2\nthree"}
+
+	diff, err := DiffRevisions(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"--- revision/1", "+++ revision/2", "-two", "+2"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("expected diff to contain %q. got %q", want, diff)
+		}
+	}
+
+	diff, err = DiffRevisions(nil, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(diff, "+# Hello") {
+		t.Errorf("expected diff from nothing to add title. got %q", diff)
+	}
+}
+
+func TestDiffRevisionsOtherPost(t *testing.T) {
+	from := &PostRevision{ID: "1", PostID: "7", Title: "Mine"}
+	to := &PostRevision{ID: "2", PostID: "8", Title: "Theirs"}
+
+	if _, err := DiffRevisions(from, to); err == nil {
+		t.Error("expected error diffing revisions of different posts")
+	}
+}
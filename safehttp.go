@@ -0,0 +1,66 @@
+package graphql
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// nonPublicPrefixes are address ranges that aren't reachable on the public
+// internet, on top of the ones the netip.Addr methods already cover.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// publicAddress returns an error if address, a host:port with an IP host, is
+// not a public unicast address.
+func publicAddress(address string) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := ap.Addr().Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return fmt.Errorf("%s is not a public address", ip)
+	}
+
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return fmt.Errorf("%s is not a public address", ip)
+		}
+	}
+
+	return nil
+}
+
+// NewPublicClient returns a client that only connects to public addresses.
+// The check runs on every connection after DNS has been resolved, so it also
+// covers redirects and hostnames that resolve to internal addresses. Use it
+// to fetch URLs that strangers give us.
+func NewPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			return publicAddress(address)
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
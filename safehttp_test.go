@@ -0,0 +1,56 @@
+package graphql
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34:80":            true,
+		"[2606:2800:220:1::]:443":     true,
+		"127.0.0.1:80":                false,
+		"10.0.0.1:80":                 false,
+		"172.16.0.1:80":               false,
+		"192.168.1.1:80":              false,
+		"169.254.169.254:80":          false,
+		"100.64.0.1:80":               false,
+		"0.0.0.0:80":                  false,
+		"224.0.0.1:80":                false,
+		"[::1]:80":                    false,
+		"[fd00::1]:80":                false,
+		"[fe80::1]:80":                false,
+		"[::ffff:127.0.0.1]:80":       false,
+		"[::ffff:169.254.169.254]:80": false,
+		"[64:ff9b::a00:1]:80":         false,
+	}
+
+	for address, public := range tests {
+		address, public := address, public // capture range variables
+		t.Run(address, func(t *testing.T) {
+			t.Parallel()
+			err := publicAddress(address)
+			if public && err != nil {
+				t.Errorf("publicAddress(%q) = %v, want nil", address, err)
+			}
+			if !public && err == nil {
+				t.Errorf("publicAddress(%q) = nil, want error", address)
+			}
+		})
+	}
+}
+
+func TestNewPublicClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request to %s reached a loopback server", r.URL)
+	}))
+	defer srv.Close()
+
+	resp, err := NewPublicClient(time.Second).Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Errorf("Get(%q) succeeded, want an error", srv.URL)
+	}
+}
@@ -2,22 +2,177 @@ package graphql
 
 import (
 	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// headlineStart and headlineStop mark matches in snippets from
+	// ts_headline. They are control characters rather than tags, so the
+	// snippet can be escaped before the matches are wrapped in <b> tags.
+	headlineStart = "\x01"
+	headlineStop  = "\x02"
+
+	// headlineOptions are the ts_headline options used for search snippets.
+	headlineOptions = `StartSel="` + headlineStart + `", StopSel="` + headlineStop + `", MaxWords=35, MinWords=15, MaxFragments=2`
 )
 
-// Search searches for posts that have matching titles, content or tags
+// SearchResult is a single match for a search, along with how well it matched
+// and a snippet of the matching text.
+type SearchResult struct {
+	Type    SearchType `json:"type"`
+	ID      string     `json:"id"`
+	Rank    float64    `json:"rank"`
+	Snippet string     `json:"snippet"`
+}
+
+// Item returns the thing that matched the search.
+func (r *SearchResult) Item(ctx context.Context) (Searchable, error) {
+	switch r.Type {
+	case SearchTypePost:
+		id, err := strconv.ParseInt(r.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid post id %q: %w", r.ID, err)
+		}
+
+		p, err := LoadPost(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if p == nil {
+			return nil, fmt.Errorf("no post %s", r.ID)
+		}
+
+		return p, nil
+	case SearchTypeLink:
+		return LoadLink(ctx, r.ID)
+	case SearchTypeTweet:
+		tweets, err := LoadTweets(ctx, []string{r.ID})
+		if err != nil {
+			return nil, err
+		}
+
+		if tweets[0] == nil {
+			return nil, fmt.Errorf("no tweet %s", r.ID)
+		}
+
+		return tweets[0], nil
+	case SearchTypeBook:
+		return GetBook(ctx, r.ID)
+	default:
+		return nil, fmt.Errorf("unknown search type %q", r.Type)
+	}
+}
+
+// Search searches for posts that have matching titles, content or tags. The
+// best matches are returned first.
 func Search(ctx context.Context, searchQuery string, limit int, offset int) ([]*Post, error) {
 	query := `
-SELECT id, title, content, date, created_at, modified_at, tags, draft
-FROM posts
-WHERE id in (
-  SELECT id
-  FROM posts, plainto_tsquery($1) query, to_tsvector(title || ' ' || content || array_to_tsvector(tags)) textsearch
-  WHERE draft = false
-    AND date <= NOW()
-    AND query @@ textsearch
-  ORDER BY ts_rank_cd(textsearch, query) DESC
-)
+SELECT id, title, content, date, created_at, modified_at, tags, draft, slug
+FROM posts, plainto_tsquery('english', $1) query
+WHERE draft = false
+  AND date <= NOW()
+  AND query @@ search_vector
+ORDER BY ts_rank_cd(search_vector, query) DESC, date DESC
 LIMIT $2 OFFSET $3
 `
 	return postQuery(ctx, query, searchQuery, limit, offset)
 }
+
+// SearchAll searches published posts, links, tweets and books at once, best
+// matches first. If types is empty, all types are searched.
+func SearchAll(ctx context.Context, searchQuery string, types []SearchType, limit int, offset int) ([]*SearchResult, error) {
+	if len(types) == 0 {
+		types = AllSearchType
+	}
+
+	ts := make([]string, len(types))
+	for i, t := range types {
+		ts[i] = t.String()
+	}
+
+	rows, err := db.QueryContext(ctx, `
+WITH query AS (SELECT plainto_tsquery('english', $1) AS q)
+SELECT type, id, rank, snippet FROM (
+  SELECT 'post' AS type, id::text AS id, ts_rank_cd(search_vector, q) AS rank,
+    ts_headline('english', title || ' ' || content, q, $2) AS snippet
+  FROM posts, query
+  WHERE 'post' = ANY($3) AND draft = false AND date <= NOW() AND q @@ search_vector
+UNION ALL
+  SELECT 'link', id::text, ts_rank_cd(search_vector, q),
+    ts_headline('english', COALESCE(title, '') || ' ' || COALESCE(description, ''), q, $2)
+  FROM links, query
+  WHERE 'link' = ANY($3) AND q @@ search_vector
+UNION ALL
+  SELECT 'tweet', id, ts_rank_cd(search_vector, q),
+    ts_headline('english', COALESCE(text, ''), q, $2)
+  FROM tweets, query
+  WHERE 'tweet' = ANY($3) AND q @@ search_vector
+UNION ALL
+  SELECT 'book', id, ts_rank_cd(search_vector, q),
+    ts_headline('english', COALESCE(title, ''), q, $2)
+  FROM books, query
+  WHERE 'book' = ANY($3) AND q @@ search_vector
+) results
+ORDER BY rank DESC, type, id
+LIMIT $4 OFFSET $5
+`, searchQuery, headlineOptions, pq.Array(ts), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*SearchResult, 0)
+	for rows.Next() {
+		r := new(SearchResult)
+		if err := rows.Scan(&r.Type, &r.ID, &r.Rank, &r.Snippet); err != nil {
+			return nil, err
+		}
+		r.Snippet = headlineHTML(r.Snippet)
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// headlineHTML turns a snippet from ts_headline into HTML. The text is
+// escaped, since links, tweets and books come from other sites, and only the
+// matches are wrapped in <b> tags. Stray markers are dropped, so the tags are
+// always balanced.
+func headlineHTML(snippet string) string {
+	var b strings.Builder
+	open := false
+	for {
+		i := strings.IndexAny(snippet, headlineStart+headlineStop)
+		if i < 0 {
+			b.WriteString(html.EscapeString(snippet))
+			break
+		}
+
+		b.WriteString(html.EscapeString(snippet[:i]))
+		switch {
+		case snippet[i:i+1] == headlineStart && !open:
+			b.WriteString("<b>")
+			open = true
+		case snippet[i:i+1] == headlineStop && open:
+			b.WriteString("</b>")
+			open = false
+		}
+		snippet = snippet[i+1:]
+	}
+
+	if open {
+		b.WriteString("</b>")
+	}
+
+	return b.String()
+}
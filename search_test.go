@@ -0,0 +1,52 @@
+package graphql
+
+import "testing"
+
+func TestSearchableSummary(t *testing.T) {
+	tests := map[string]struct {
+		item Searchable
+		want string
+	}{
+		"link":              {item: &Link{Title: "Title", Description: "Description"}, want: "Description"},
+		"link without desc": {item: &Link{Title: "Title"}, want: "Title"},
+		"tweet":             {item: &Tweet{Text: "hello world"}, want: "hello world"},
+		"book":              {item: &Book{Title: "Dune"}, want: "Dune"},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.item.GetSummary(); got != tc.want {
+				t.Errorf("GetSummary() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHeadlineHTML(t *testing.T) {
+	tests := map[string]struct {
+		snippet string
+		want    string
+	}{
+		"match": {snippet: "a \x01great\x02 link", want: "a <b>great</b> link"},
+		"script in description": {
+			snippet: "\x01Cool\x02 site <script>alert(1)</script>",
+			want:    "<b>Cool</b> site &lt;script&gt;alert(1)&lt;/script&gt;",
+		},
+		"html in match": {snippet: "\x01<img src=x onerror=alert(1)>\x02", want: "<b>&lt;img src=x onerror=alert(1)&gt;</b>"},
+		"tags in text":  {snippet: "<b>not ours</b> \x01word\x02", want: "&lt;b&gt;not ours&lt;/b&gt; <b>word</b>"},
+		"stray markers": {snippet: "\x02a \x01b \x01c", want: "a <b>b c</b>"},
+		"no matches":    {snippet: "Tom & Jerry", want: "Tom &amp; Jerry"},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := headlineHTML(tc.snippet); got != tc.want {
+				t.Errorf("headlineHTML(%q) = %q, want %q", tc.snippet, got, tc.want)
+			}
+		})
+	}
+}
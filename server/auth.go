@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"time"
 
 	jwtmiddleware "github.com/auth0/go-jwt-middleware"
 	"github.com/form3tech-oss/jwt-go"
@@ -11,58 +14,59 @@ import (
 	"go.uber.org/zap"
 )
 
-// Jwks is from https://auth0.com/docs/quickstart/backend/golang
-type Jwks struct {
-	Keys []JSONWebKeys `json:"keys"`
-}
+var (
+	// tokenVerifier checks JWTs. Set the provider with OIDC_ISSUER,
+	// OIDC_AUDIENCE and OIDC_JWKS_URL. If OIDC_JWKS_URL is empty, it is found
+	// with OpenID Connect discovery on the issuer.
+	tokenVerifier = &graphql.TokenVerifier{
+		Issuer:   envString("OIDC_ISSUER", "https://icco.auth0.com/"),
+		Audience: envString("OIDC_AUDIENCE", "https://natwelch.com"),
+	}
 
-// JSONWebKeys is from https://auth0.com/docs/quickstart/backend/golang
-type JSONWebKeys struct {
-	Kty string   `json:"kty"`
-	Kid string   `json:"kid"`
-	Use string   `json:"use"`
-	N   string   `json:"n"`
-	E   string   `json:"e"`
-	X5c []string `json:"x5c"`
-}
+	// jwksInterval is how often the signing keys are refreshed, to pick up
+	// rotated keys. Set with JWKS_REFRESH_INTERVAL, such as "1h".
+	jwksInterval = 15 * time.Minute
+
+	jwksClient = &http.Client{Timeout: 10 * time.Second}
+)
 
-// CustomClaims is from https://auth0.com/docs/quickstart/backend/golang
-type CustomClaims struct {
-	Scope     string   `json:"scope"`
-	Audience  []string `json:"aud,omitempty"`
-	ExpiresAt int64    `json:"exp,omitempty"`
-	ID        string   `json:"jti,omitempty"`
-	IssuedAt  int64    `json:"iat,omitempty"`
-	Issuer    string   `json:"iss,omitempty"`
-	NotBefore int64    `json:"nbf,omitempty"`
-	Subject   string   `json:"sub,omitempty"`
+func init() {
+	tokenVerifier.Keys = graphql.NewJWKS(jwksClient, os.Getenv("OIDC_JWKS_URL"), tokenVerifier.Issuer)
+
+	if fromEnv := os.Getenv("JWKS_REFRESH_INTERVAL"); fromEnv != "" {
+		d, err := time.ParseDuration(fromEnv)
+		if err != nil || d <= 0 {
+			log.Warnw("invalid JWKS_REFRESH_INTERVAL, using default", "JWKS_REFRESH_INTERVAL", fromEnv, "default", jwksInterval)
+			return
+		}
+		jwksInterval = d
+	}
 }
 
-func (c CustomClaims) toStandard() jwt.StandardClaims {
-	return jwt.StandardClaims{
-		Audience:  c.Audience,
-		ExpiresAt: c.ExpiresAt,
-		Id:        c.ID,
-		IssuedAt:  c.IssuedAt,
-		Issuer:    c.Issuer,
-		NotBefore: c.NotBefore,
-		Subject:   c.Subject,
+func envString(name, def string) string {
+	if fromEnv, ok := os.LookupEnv(name); ok {
+		return fromEnv
 	}
+
+	return def
 }
 
-// Valid is required for conformance to jwt.Claims.
-func (c CustomClaims) Valid() error {
-	return c.toStandard().Valid()
+// runJWKSRefresh refreshes the JWT signing keys until the context is done.
+func runJWKSRefresh(ctx context.Context) {
+	tokenVerifier.Keys.Run(ctx, jwksInterval, func(err error) {
+		log.Errorw("could not refresh jwks", zap.Error(err))
+	})
 }
 
 // APIKeyMiddleware is an auth middleware. If user is coming in via api key
-// header, use that as your auth.
+// header, use that as your auth. The key is put in the context too, so its
+// scopes can be checked.
 func APIKeyMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// API Key dropout
 		if r.Header.Get("X-API-AUTH") != "" {
 			apikey := r.Header.Get("X-API-AUTH")
-			user, err := graphql.GetUserByAPIKey(r.Context(), apikey)
+			user, key, err := graphql.GetUserByAPIKey(r.Context(), apikey)
 			if err != nil {
 				log.Errorw("could not get user by apikey", zap.Error(err))
 				http.Error(w, `{"error": "could not get a user with that API key"}`, http.StatusBadRequest)
@@ -70,7 +74,7 @@ func APIKeyMiddleware(next http.Handler) http.Handler {
 			}
 
 			// put it in context
-			ctx := graphql.WithUser(r.Context(), user)
+			ctx := graphql.WithAPIKey(graphql.WithUser(r.Context(), user), key)
 			r = r.WithContext(ctx)
 		}
 
@@ -92,38 +96,15 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	jwtMiddleware := jwtmiddleware.New(jwtmiddleware.Options{
 		CredentialsOptional: true,
 		ValidationKeyGetter: func(token *jwt.Token) (interface{}, error) {
-			// Verify 'aud' claim
-			aud := "https://natwelch.com"
-			checkAud := token.Claims.(jwt.MapClaims).VerifyAudience(aud, false)
-			if !checkAud {
-				log.Errorw("invalid audence", "aud", token.Raw)
-				return token, jsonError("Invalid audience.")
-			}
-			// Verify 'iss' claim
-			iss := "https://icco.auth0.com/"
-			checkIss := token.Claims.(jwt.MapClaims).VerifyIssuer(iss, false)
-			if !checkIss {
-				log.Errorw("invalid issuer", "iss", token.Raw)
-				return token, jsonError("Invalid issuer.")
-			}
-
-			cert, err := getPemCert(token)
+			key, err := tokenVerifier.Keyfunc(token)
 			if err != nil {
-				msg := "cloudn't parse pem cert"
-				log.Errorw(msg, zap.Error(err))
-				return token, jsonError(msg)
+				log.Errorw("invalid token", zap.Error(err))
+				return nil, jsonError(err.Error())
 			}
-
-			data, err := jwt.ParseRSAPublicKeyFromPEM([]byte(cert))
-			if err != nil {
-				log.Errorw("error parsing cert", zap.Error(err), "cert", cert)
-				return token, jsonError(err.Error())
-			}
-			return data, nil
+			return key, nil
 		},
-		SigningMethod: jwt.SigningMethodRS256,
 		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err string) {
-			log.Errorw("error with auth", zap.Error(fmt.Errorf(err)))
+			log.Errorw("error with auth", "error", err)
 			w.Header().Set("Content-Type", "application/json; charset=utf-8")
 			w.Header().Set("X-Content-Type-Options", "nosniff")
 			w.WriteHeader(http.StatusBadRequest)
@@ -134,37 +115,30 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	return jwtMiddleware.Handler(getUserFromToken(next))
 }
 
+// getUserFromToken attaches the user for the token the jwt middleware
+// verified, if there was one.
 func getUserFromToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString, err := jwtmiddleware.FromAuthHeader(r)
-		if err != nil {
-			log.Errorw("could not get auth header", zap.Error(err))
+		token, ok := r.Context().Value("user").(*jwt.Token)
+		if !ok {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		claims := &CustomClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			cert, err := getPemCert(token)
-			if err != nil {
-				return nil, err
-			}
-			return jwt.ParseRSAPublicKeyFromPEM([]byte(cert))
-		})
-
-		// No claims, that's cool.
-		if err != nil {
-			// log.Debugw("could not get claims", zap.Error(err))
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		log.Debugw("the token", "token", token, "claims", claims)
-
-		if claims.Subject != "" {
-			user, err := graphql.GetUser(r.Context(), claims.Subject)
+		if sub, _ := claims["sub"].(string); sub != "" {
+			user, err := graphql.GetUser(r.Context(), sub)
 			if err != nil {
-				log.Errorw("could not get user", "claims", claims, zap.Error(err))
+				log.Errorw("could not get user", "sub", sub, zap.Error(err))
+			} else if err := user.CheckEnabled(); err != nil {
+				log.Warnw("disabled user tried to sign in", "sub", sub)
+				http.Error(w, `{"error": "this user is disabled"}`, http.StatusForbidden)
+				return
 			} else {
 				// put it in context
 				ctx := graphql.WithUser(r.Context(), user)
@@ -175,33 +149,3 @@ func getUserFromToken(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
-
-func getPemCert(token *jwt.Token) (string, error) {
-	cert := ""
-	resp, err := http.Get("https://icco.auth0.com/.well-known/jwks.json")
-
-	if err != nil {
-		return cert, err
-	}
-	defer resp.Body.Close()
-
-	var jwks = Jwks{}
-	err = json.NewDecoder(resp.Body).Decode(&jwks)
-
-	if err != nil {
-		return cert, err
-	}
-
-	for _, k := range jwks.Keys {
-		if token.Header["kid"] == k.Kid {
-			cert = fmt.Sprintf("-----BEGIN CERTIFICATE-----\n%s\n-----END CERTIFICATE-----", k.X5c[0])
-		}
-	}
-
-	if cert == "" {
-		err := fmt.Errorf("unable to find appropriate key")
-		return cert, err
-	}
-
-	return cert, nil
-}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/feeds"
+	"github.com/icco/graphql"
+	"go.uber.org/zap"
+)
+
+const maxFeedSize = 100
+
+var (
+	// feedSize is the default number of posts in a feed. It can be set with
+	// the FEED_SIZE environment variable and overridden per request with the
+	// count query parameter.
+	feedSize = 20
+
+	feedContentTypes = map[string]string{
+		"atom": "application/atom+xml; charset=utf-8",
+		"rss":  "application/rss+xml; charset=utf-8",
+		"json": "application/feed+json; charset=utf-8",
+	}
+)
+
+func init() {
+	if fromEnv := os.Getenv("FEED_SIZE"); fromEnv != "" {
+		i, err := strconv.Atoi(fromEnv)
+		if err != nil || i <= 0 {
+			log.Warnw("invalid FEED_SIZE, using default", "FEED_SIZE", fromEnv, "default", feedSize)
+			return
+		}
+		feedSize = i
+	}
+}
+
+func feedCount(r *http.Request) int {
+	count := feedSize
+	if c := r.URL.Query().Get("count"); c != "" {
+		if i, err := strconv.Atoi(c); err == nil && i > 0 {
+			count = i
+		}
+	}
+
+	if count > maxFeedSize {
+		count = maxFeedSize
+	}
+
+	return count
+}
+
+func feedHandler(w http.ResponseWriter, r *http.Request) {
+	format := chi.URLParam(r, "format")
+	if _, ok := feedContentTypes[format]; !ok {
+		notFoundHandler(w, r)
+		return
+	}
+
+	posts, err := graphql.Posts(r.Context(), feedCount(r), 0)
+	if err != nil {
+		log.Errorw("could not get posts for feed", zap.Error(err))
+		internalErrorHandler(w, r)
+		return
+	}
+
+	f := graphql.NewFeed(graphql.FeedTitle, graphql.FeedLink, posts)
+	writeFeed(w, r, format, f)
+}
+
+func tagFeedHandler(w http.ResponseWriter, r *http.Request) {
+	format := chi.URLParam(r, "format")
+	if _, ok := feedContentTypes[format]; !ok {
+		notFoundHandler(w, r)
+		return
+	}
+
+	tag := chi.URLParam(r, "tag")
+	posts, err := graphql.PostsByTag(r.Context(), tag, feedCount(r))
+	if err != nil {
+		log.Errorw("could not get posts for tag feed", "tag", tag, zap.Error(err))
+		internalErrorHandler(w, r)
+		return
+	}
+
+	f := graphql.NewFeed(
+		fmt.Sprintf("%s: #%s", graphql.FeedTitle, tag),
+		fmt.Sprintf("%s/tags/%s", graphql.FeedLink, tag),
+		posts)
+	writeFeed(w, r, format, f)
+}
+
+// writeFeed renders a feed, answering conditional requests with a 304 if the
+// client already has the current version.
+func writeFeed(w http.ResponseWriter, r *http.Request, format string, f *feeds.Feed) {
+	etag := graphql.FeedETag(format, f)
+	modified := f.Updated.UTC().Truncate(time.Second)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if notModified(r, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", feedContentTypes[format])
+
+	var err error
+	switch format {
+	case "atom":
+		err = f.WriteAtom(w)
+	case "rss":
+		err = f.WriteRss(w)
+	case "json":
+		err = f.WriteJSON(w)
+	}
+
+	if err != nil {
+		log.Errorw("could not write feed", "format", format, zap.Error(err))
+	}
+}
+
+// notModified checks If-None-Match and If-Modified-Since. If-None-Match takes
+// precedence as described in RFC 7232.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+			if t == etag || t == "*" {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+
+		return !modified.After(t)
+	}
+
+	return false
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/icco/graphql"
+)
+
+var (
+	// maxComplexity is the most complex operation allowed. Set with
+	// GRAPHQL_MAX_COMPLEXITY.
+	maxComplexity = envInt("GRAPHQL_MAX_COMPLEXITY", 1000)
+
+	// maxDepth is the deepest fields can be nested. Set with GRAPHQL_MAX_DEPTH.
+	maxDepth = envInt("GRAPHQL_MAX_DEPTH", 10)
+
+	// trustedProxies is how many entries at the end of X-Forwarded-For were
+	// added by our own proxies. Set with TRUSTED_PROXIES. The default of one
+	// is the Google Cloud load balancer.
+	trustedProxies = envCount("TRUSTED_PROXIES", 1)
+
+	// rateLimitEnv lists the environment variable and default rate limit for
+	// each kind of client.
+	rateLimitEnv = []struct {
+		kind graphql.RateLimitKind
+		name string
+		def  string
+	}{
+		{graphql.RateLimitIP, "RATE_LIMIT_IP", "2/30"},
+		{graphql.RateLimitUser, "RATE_LIMIT_USER", "5/60"},
+		{graphql.RateLimitAPIKey, "RATE_LIMIT_APIKEY", "20/200"},
+	}
+)
+
+func envInt(name string, def int) int {
+	fromEnv := os.Getenv(name)
+	if fromEnv == "" {
+		return def
+	}
+
+	i, err := strconv.Atoi(fromEnv)
+	if err != nil || i < 1 {
+		log.Warnw("invalid integer in environment, using default", name, fromEnv, "default", def)
+		return def
+	}
+
+	return i
+}
+
+// envCount is like envInt, but allows zero.
+func envCount(name string, def int) int {
+	fromEnv := os.Getenv(name)
+	if fromEnv == "" {
+		return def
+	}
+
+	i, err := strconv.Atoi(fromEnv)
+	if err != nil || i < 0 {
+		log.Warnw("invalid integer in environment, using default", name, fromEnv, "default", def)
+		return def
+	}
+
+	return i
+}
+
+// clientIP returns the address of whoever connected to the first of our
+// proxies. Clients can put anything in X-Forwarded-For, but each proxy
+// appends the address it was connected from, so the client is the entry just
+// before the ones our proxies added. If there aren't enough entries, or there
+// are no proxies, the address of the connection is used.
+func clientIP(remoteAddr string, forwardedFor []string, proxies int) string {
+	ip := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		ip = host
+	}
+
+	if proxies < 1 {
+		return ip
+	}
+
+	var hops []string
+	for _, h := range forwardedFor {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+
+	if len(hops) <= proxies {
+		return ip
+	}
+
+	hop := strings.TrimSpace(hops[len(hops)-1-proxies])
+	if net.ParseIP(hop) == nil {
+		return ip
+	}
+
+	return hop
+}
+
+// RealIPMiddleware sets the request's RemoteAddr to the client address found
+// by clientIP, so logs and rate limits see the client rather than our load
+// balancer.
+func RealIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.RemoteAddr = clientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), trustedProxies)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fieldCosts returns the default field costs, overridden by
+// GRAPHQL_FIELD_COSTS, such as "Post.related=50,Query.search=5".
+func fieldCosts() map[string]int {
+	costs := map[string]int{}
+	for k, v := range graphql.DefaultFieldCosts {
+		costs[k] = v
+	}
+
+	overrides, err := graphql.ParseFieldCosts(os.Getenv("GRAPHQL_FIELD_COSTS"))
+	if err != nil {
+		log.Fatalw("invalid GRAPHQL_FIELD_COSTS", "error", err)
+	}
+
+	for k, v := range overrides {
+		costs[k] = v
+	}
+
+	return costs
+}
+
+// rateLimits reads RATE_LIMIT_IP, RATE_LIMIT_USER and RATE_LIMIT_APIKEY, each
+// of the form "per second/burst". Setting one to "off" disables that limit.
+func rateLimits() map[graphql.RateLimitKind]graphql.RateLimit {
+	limits := map[graphql.RateLimitKind]graphql.RateLimit{}
+	for _, e := range rateLimitEnv {
+		s := os.Getenv(e.name)
+		switch s {
+		case "off":
+			continue
+		case "":
+			s = e.def
+		}
+
+		l, err := graphql.ParseRateLimit(s)
+		if err != nil {
+			log.Fatalw("invalid rate limit", "name", e.name, "error", err)
+		}
+		limits[e.kind] = l
+	}
+
+	return limits
+}
+
+// RateLimitKeyMiddleware decides who a request is rate limited as: its API
+// key, its user, or its IP address. It must run after the auth middlewares
+// and RealIPMiddleware.
+func RateLimitKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k := graphql.RateLimitKey{Kind: graphql.RateLimitIP, ID: r.RemoteAddr}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			k.ID = host
+		}
+
+		if apikey := r.Header.Get("X-API-AUTH"); apikey != "" {
+			// Do not keep API keys around in memory.
+			sum := sha256.Sum256([]byte(apikey))
+			k = graphql.RateLimitKey{Kind: graphql.RateLimitAPIKey, ID: hex.EncodeToString(sum[:])}
+		} else if u := graphql.GetUserFromContext(r.Context()); u != nil {
+			k = graphql.RateLimitKey{Kind: graphql.RateLimitUser, ID: u.ID}
+		}
+
+		next.ServeHTTP(w, r.WithContext(graphql.WithRateLimitKey(r.Context(), k)))
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := map[string]struct {
+		remoteAddr   string
+		forwardedFor []string
+		proxies      int
+		want         string
+	}{
+		"no proxies":          {"10.0.0.1:1234", []string{"1.2.3.4"}, 0, "10.0.0.1"},
+		"load balancer":       {"10.0.0.1:1234", []string{"1.2.3.4, 35.1.1.1"}, 1, "1.2.3.4"},
+		"spoofed":             {"10.0.0.1:1234", []string{"6.6.6.6, 1.2.3.4, 35.1.1.1"}, 1, "1.2.3.4"},
+		"spoofed headers":     {"10.0.0.1:1234", []string{"6.6.6.6", "1.2.3.4, 35.1.1.1"}, 1, "1.2.3.4"},
+		"two proxies":         {"10.0.0.1:1234", []string{"1.2.3.4, 35.1.1.1, 10.0.0.2"}, 2, "1.2.3.4"},
+		"ipv6":                {"10.0.0.1:1234", []string{"2001:db8::1,35.1.1.1"}, 1, "2001:db8::1"},
+		"too few":             {"10.0.0.1:1234", []string{"35.1.1.1"}, 1, "10.0.0.1"},
+		"no header":           {"10.0.0.1:1234", nil, 1, "10.0.0.1"},
+		"not an ip":           {"10.0.0.1:1234", []string{"nonsense, 35.1.1.1"}, 1, "10.0.0.1"},
+		"remote without port": {"10.0.0.1", nil, 1, "10.0.0.1"},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := clientIP(tc.remoteAddr, tc.forwardedFor, tc.proxies); got != tc.want {
+				t.Errorf("clientIP(%q, %q, %d) = %q, want %q", tc.remoteAddr, tc.forwardedFor, tc.proxies, got, tc.want)
+			}
+		})
+	}
+}
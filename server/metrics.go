@@ -0,0 +1,114 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/icco/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// maxOperationNameLength is the longest operation name used as a label. Names
+// come from clients, so this keeps one bad client from making huge series.
+const maxOperationNameLength = 100
+
+// maxOperationNames is how many distinct operation names get their own
+// series. Operations with names seen after that are counted as "other".
+const maxOperationNames = 100
+
+var (
+	gqlOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "graphql_operations_total",
+		Help: "GraphQL operations handled, by operation name and type.",
+	}, []string{"operation", "type"})
+
+	gqlOperationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "graphql_operation_errors_total",
+		Help: "GraphQL operations that returned errors, by operation name and type.",
+	}, []string{"operation", "type"})
+
+	gqlOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "graphql_operation_duration_seconds",
+		Help:    "How long GraphQL operations took, by operation name and type.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "type"})
+
+	gqlOperationNames = newOperationNames(maxOperationNames)
+)
+
+// operationNames decides which operation names are used as labels. The first
+// max names seen are kept, and the rest become "other", so clients can't
+// make an unbounded number of series.
+type operationNames struct {
+	mu    sync.Mutex
+	max   int
+	names map[string]bool
+}
+
+func newOperationNames(max int) *operationNames {
+	return &operationNames{max: max, names: map[string]bool{}}
+}
+
+// label returns the label to use for the operation name.
+func (o *operationNames) label(name string) string {
+	if name == "" {
+		return "anonymous"
+	}
+	if len(name) > maxOperationNameLength {
+		name = name[:maxOperationNameLength]
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.names[name] {
+		if len(o.names) >= o.max {
+			return "other"
+		}
+		o.names[name] = true
+	}
+
+	return name
+}
+
+// metricsHandler returns the handler for /metrics, which serves Go runtime
+// and process metrics, GraphQL operation metrics, connection pool stats for
+// sqlDB, and the latest value of every stat. Stat keys can be turned into
+// labels with STAT_METRIC_LABELS, such as "weather.*.temp=weather_temp:city".
+func metricsHandler(sqlDB *sql.DB) http.Handler {
+	rules, err := graphql.ParseStatLabelRules(os.Getenv("STAT_METRIC_LABELS"))
+	if err != nil {
+		log.Warnw("invalid STAT_METRIC_LABELS, ignoring", "STAT_METRIC_LABELS", os.Getenv("STAT_METRIC_LABELS"), zap.Error(err))
+		rules = nil
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(sqlDB, "graphql"),
+		gqlOperations,
+		gqlOperationErrors,
+		gqlOperationDuration,
+		&graphql.StatCollector{Rules: rules},
+	)
+
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{ErrorLog: zap.NewStdLog(log.Desugar())})
+}
+
+// observeOperation records a finished GraphQL operation.
+func observeOperation(name, typ string, took time.Duration, failed bool) {
+	name = gqlOperationNames.label(name)
+
+	gqlOperations.WithLabelValues(name, typ).Inc()
+	gqlOperationDuration.WithLabelValues(name, typ).Observe(took.Seconds())
+	if failed {
+		gqlOperationErrors.WithLabelValues(name, typ).Inc()
+	}
+}
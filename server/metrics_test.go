@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOperationNamesLabel(t *testing.T) {
+	o := newOperationNames(2)
+
+	long := strings.Repeat("a", maxOperationNameLength+10)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", "anonymous"},
+		{"GetPosts", "GetPosts"},
+		{long, long[:maxOperationNameLength]},
+		{"GetLinks", "other"},
+		{"GetPosts", "GetPosts"},
+		{long + "b", long[:maxOperationNameLength]},
+		{"", "anonymous"},
+	}
+
+	for _, tc := range tests {
+		if got := o.label(tc.name); got != tc.want {
+			t.Errorf("label(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -20,6 +20,16 @@ func photoUploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := graphql.CheckScope(ctx, graphql.ScopePhotosWrite); err != nil {
+		err := Renderer.JSON(w, http.StatusForbidden, map[string]string{
+			"error": "403: " + err.Error(),
+		})
+		if err != nil {
+			log.Errorw("could not render json", zap.Error(err))
+		}
+		return
+	}
+
 	file, header, err := r.FormFile("file")
 	if err == http.ErrMissingFile {
 		err := Renderer.JSON(w, http.StatusBadRequest, map[string]string{
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/icco/graphql"
+	"go.uber.org/zap"
+)
+
+var (
+	// publishInterval is how often the scheduler checks for posts whose
+	// publish date has passed. Set with PUBLISH_INTERVAL, such as "30s".
+	publishInterval = time.Minute
+
+	// publishPurgeURLs are cached URLs, like feeds, that get a PURGE request
+	// every time a post is published. Set with PUBLISH_PURGE_URLS as a comma
+	// separated list.
+	publishPurgeURLs = splitEnv("PUBLISH_PURGE_URLS")
+
+	hookClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+func init() {
+	if fromEnv := os.Getenv("PUBLISH_INTERVAL"); fromEnv != "" {
+		d, err := time.ParseDuration(fromEnv)
+		if err != nil || d <= 0 {
+			log.Warnw("invalid PUBLISH_INTERVAL, using default", "PUBLISH_INTERVAL", fromEnv, "default", publishInterval)
+			return
+		}
+		publishInterval = d
+	}
+}
+
+func splitEnv(name string) []string {
+	var ret []string
+	for _, s := range strings.Split(os.Getenv(name), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			ret = append(ret, s)
+		}
+	}
+
+	return ret
+}
+
+// runScheduler publishes scheduled posts until the context is done.
+func runScheduler(ctx context.Context) {
+	runEvery(ctx, publishInterval, publishTick)
+}
+
+// backfillPostLinks saves the links of posts written before links were
+// tracked, so they show up on Post.links and Link.posts.
+func backfillPostLinks(ctx context.Context) {
+	n, err := graphql.BackfillPostLinks(ctx)
+	if n > 0 {
+		log.Infow("backfilled post links", "count", n)
+	}
+	if err != nil {
+		log.Errorw("could not backfill post links", zap.Error(err))
+	}
+}
+
+// runEvery calls f right away, then every interval, until the context is
+// done. Calls never overlap, so a slow call delays the next one.
+func runEvery(ctx context.Context, interval time.Duration, f func(context.Context)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		f(ctx)
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func publishTick(ctx context.Context) {
+	n, err := graphql.RecordDuePublications(ctx)
+	if err != nil {
+		log.Errorw("could not record due publications", zap.Error(err))
+	} else if n > 0 {
+		log.Infow("published scheduled posts", "count", n)
+	}
+
+	pubs, err := graphql.ClaimPublications(ctx)
+	if err != nil {
+		log.Errorw("could not claim publications", zap.Error(err))
+		return
+	}
+
+	for _, pub := range pubs {
+		p, err := pub.Post(ctx)
+		if err != nil || p == nil {
+			log.Errorw("could not get published post", "id", pub.PostID, zap.Error(err))
+			continue
+		}
+
+		purgeCaches(ctx, p)
+
+		n, err := graphql.SendPostWebmentions(ctx, webmentionClient, p)
+		if err != nil {
+			log.Errorw("could not send webmentions", "post", p.ID, zap.Error(err))
+		} else if n > 0 {
+			log.Infow("sent webmentions", "post", p.ID, "count", n)
+		}
+	}
+}
+
+func purgeCaches(ctx context.Context, p *graphql.Post) {
+	for _, u := range publishPurgeURLs {
+		req, err := http.NewRequestWithContext(ctx, "PURGE", u, nil)
+		if err != nil {
+			log.Errorw("could not build purge request", "url", u, zap.Error(err))
+			continue
+		}
+
+		if err := doHook(req); err != nil {
+			log.Errorw("could not purge cache", "url", u, "post", p.ID, zap.Error(err))
+		}
+	}
+}
+
+func doHook(req *http.Request) error {
+	resp, err := hookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: got status %d", req.Method, req.URL, resp.StatusCode)
+	}
+
+	return nil
+}
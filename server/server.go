@@ -2,14 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	gql "github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/apollotracing"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
@@ -56,10 +59,30 @@ func main() {
 		log.Fatal("DATABASE_URL is empty!")
 	}
 
-	if _, err := graphql.InitDB(dbURL); err != nil {
+	sqlDB, err := graphql.InitDB(dbURL)
+	if err != nil {
 		log.Fatalw("Init DB", zap.Error(err))
 	}
 
+	if err := graphql.ListenForNotifications(context.Background(), dbURL); err != nil {
+		log.Fatalw("Listen for notifications", zap.Error(err))
+	}
+
+	go runScheduler(context.Background())
+	go backfillPostLinks(context.Background())
+	go runWebhookDelivery(context.Background())
+	go runJWKSRefresh(context.Background())
+	go runStatCompaction(context.Background())
+	go runWebmentionQueue(context.Background())
+
+	if fromEnv := os.Getenv("COMMENTS_AUTO_APPROVE"); fromEnv != "" {
+		autoApprove, err := strconv.ParseBool(fromEnv)
+		if err != nil {
+			log.Fatalw("invalid COMMENTS_AUTO_APPROVE", "COMMENTS_AUTO_APPROVE", fromEnv, zap.Error(err))
+		}
+		graphql.AutoApproveComments = autoApprove
+	}
+
 	port := "8080"
 	if fromEnv := os.Getenv("PORT"); fromEnv != "" {
 		port = fromEnv
@@ -73,7 +96,7 @@ func main() {
 		log.Fatalw("could not connect to cache", zap.Error(err))
 	}
 
-	gh := handler.New(graphql.NewExecutableSchema(graphql.New()))
+	gh := handler.New(graphql.WithFieldCosts(graphql.NewExecutableSchema(graphql.New()), fieldCosts()))
 	gh.AddTransport(transport.Websocket{KeepAlivePingInterval: 10 * time.Second})
 	gh.AddTransport(transport.Options{})
 	gh.AddTransport(transport.GET{})
@@ -85,17 +108,12 @@ func main() {
 	gh.Use(apollotracing.Tracer{})
 	gh.Use(extension.AutomaticPersistedQuery{Cache: cache})
 	gh.Use(extension.Introspection{})
+	gh.Use(graphql.DepthLimit{Max: maxDepth})
+	gh.Use(extension.FixedComplexityLimit(maxComplexity))
+	gh.Use(graphql.NewRateLimiter(rateLimits()))
+	gh.Use(graphql.AuditLog{})
 
-	gh.SetErrorPresenter(func(ctx context.Context, e error) *gqlerror.Error {
-		err := gql.DefaultErrorPresenter(ctx, e)
-
-		log.Warnw("graphql request error", zap.Error(e))
-		if strings.Contains(e.Error(), "forbidden") {
-			return gqlerror.Errorf("forbidden: not a valid user")
-		}
-
-		return err
-	})
+	gh.SetErrorPresenter(errorPresenter)
 	gh.SetRecoverFunc(func(ctx context.Context, err interface{}) error {
 		if e, ok := err.(error); !ok {
 			log.Errorw("graphql fatal request error", "error", err)
@@ -107,9 +125,10 @@ func main() {
 	})
 
 	gh.AroundResponses(GqlLoggingMiddleware)
+	gh.AroundResponses(LoaderMiddleware)
 
 	r := chi.NewRouter()
-	r.Use(middleware.RealIP)
+	r.Use(RealIPMiddleware)
 	r.Use(middleware.Compress(5))
 	r.Use(logging.Middleware(log.Desugar(), GCPProjectID))
 
@@ -137,6 +156,7 @@ func main() {
 		}).Handler)
 
 		r.Get("/healthz", healthCheckHandler)
+		r.Handle("/metrics", metricsHandler(sqlDB))
 	})
 
 	// Everything that does SSL only
@@ -157,19 +177,27 @@ func main() {
 		r.Use(sslOnly)
 		r.Use(APIKeyMiddleware)
 		r.Use(AuthMiddleware)
+		r.Use(RateLimitKeyMiddleware)
 
 		r.Handle("/", playground.Handler("graphql", "/graphql"))
 		r.Handle("/graphql", gh)
 
 		r.Post("/photo/new", photoUploadHandler)
+		r.Post("/stats", statLinesHandler)
+		r.Post("/webmention", webmentionHandler)
+
+		r.Get("/feed.{format}", feedHandler)
+		r.Get("/tags/{tag}/feed.{format}", tagFeedHandler)
 	})
 
 	log.Fatal(http.ListenAndServe(":"+port, r))
 }
 
-// GqlLoggingMiddleware is a middleware for gqlgen that logs all gql requests to debug.
+// GqlLoggingMiddleware is a middleware for gqlgen that logs all gql requests
+// to debug, and records their counts, latencies and errors for /metrics.
 func GqlLoggingMiddleware(ctx context.Context, next gql.ResponseHandler) *gql.Response {
 	rctx := gql.GetOperationContext(ctx)
+	start := time.Now()
 
 	// We do this because RequestContext has fields that can't be easily
 	// serialized in json, and we don't care about them.
@@ -182,7 +210,27 @@ func GqlLoggingMiddleware(ctx context.Context, next gql.ResponseHandler) *gql.Re
 
 	log.Debugw("request gql", "gql", subsetContext)
 
-	return next(ctx)
+	resp := next(ctx)
+
+	// Subscriptions end with a nil response, which isn't an operation.
+	if resp != nil {
+		typ := "unknown"
+		if rctx.Operation != nil {
+			typ = string(rctx.Operation.Operation)
+		}
+		observeOperation(rctx.OperationName, typ, time.Since(start), len(resp.Errors) > 0)
+	}
+
+	return resp
+}
+
+// LoaderMiddleware is a middleware for gqlgen that gives every response its
+// own set of loaders, so lookups made while resolving it are batched
+// together. Subscriptions get new loaders for every event, so their results
+// are never stale and their caches don't grow for the life of the
+// connection.
+func LoaderMiddleware(ctx context.Context, next gql.ResponseHandler) *gql.Response {
+	return next(graphql.WithLoaders(ctx, graphql.NewLoaders()))
 }
 
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
@@ -209,3 +257,24 @@ func notFoundHandler(w http.ResponseWriter, r *http.Request) {
 		log.Errorw("could not render json", zap.Error(err))
 	}
 }
+
+// errorPresenter hides why a user was forbidden, but passes API key scope
+// errors through with a FORBIDDEN code, so key owners can tell what is
+// missing.
+func errorPresenter(ctx context.Context, e error) *gqlerror.Error {
+	err := gql.DefaultErrorPresenter(ctx, e)
+
+	log.Warnw("graphql request error", zap.Error(e))
+
+	var serr *graphql.ScopeError
+	if errors.As(e, &serr) {
+		errcode.Set(err, "FORBIDDEN")
+		return err
+	}
+
+	if strings.Contains(e.Error(), "forbidden") {
+		return gqlerror.Errorf("forbidden: not a valid user")
+	}
+
+	return err
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/icco/graphql"
+)
+
+func TestErrorPresenter(t *testing.T) {
+	k := &graphql.APIKey{}
+	ctx := graphql.WithAPIKey(context.Background(), k)
+	scopeErr := graphql.CheckScope(ctx, graphql.ScopeStatsWrite)
+	if scopeErr == nil {
+		t.Fatal("CheckScope() = nil, want a scope error")
+	}
+
+	tests := map[string]struct {
+		err  error
+		msg  string
+		code interface{}
+	}{
+		"scope":     {scopeErr, scopeErr.Error(), "FORBIDDEN"},
+		"forbidden": {fmt.Errorf("forbidden"), "forbidden: not a valid user", nil},
+		"other":     {fmt.Errorf("no post"), "no post", nil},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := errorPresenter(context.Background(), tc.err)
+			if got.Message != tc.msg || got.Extensions["code"] != tc.code {
+				t.Errorf("errorPresenter(%q) = %q, code %v, want %q, code %v", tc.err, got.Message, got.Extensions["code"], tc.msg, tc.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/icco/graphql"
+	"go.uber.org/zap"
+)
+
+// maxStatLinesBody is the largest body accepted by statLinesHandler.
+const maxStatLinesBody = 10 << 20
+
+// statCompactionInterval is how often stat retention policies are applied.
+// Set with STAT_COMPACTION_INTERVAL, such as "6h".
+var statCompactionInterval = time.Hour
+
+func init() {
+	if fromEnv := os.Getenv("STAT_COMPACTION_INTERVAL"); fromEnv != "" {
+		d, err := time.ParseDuration(fromEnv)
+		if err != nil || d <= 0 {
+			log.Warnw("invalid STAT_COMPACTION_INTERVAL, using default", "STAT_COMPACTION_INTERVAL", fromEnv, "default", statCompactionInterval)
+			return
+		}
+		statCompactionInterval = d
+	}
+}
+
+// runStatCompaction rolls up old stats until the context is done.
+func runStatCompaction(ctx context.Context) {
+	runEvery(ctx, statCompactionInterval, compactStatsTick)
+}
+
+func compactStatsTick(ctx context.Context) {
+	n, err := graphql.CompactStats(ctx, time.Now())
+	if err != nil {
+		log.Errorw("could not compact stats", zap.Error(err))
+	} else if n > 0 {
+		log.Infow("compacted stats", "count", n)
+	}
+}
+
+// statLinesHandler saves stats sent as InfluxDB style lines, such as
+// "steps value=1000 1697000000", in one transaction. Timestamps are in
+// nanoseconds unless the precision query parameter is us, ms or s. Only
+// admins can use it, and API keys need the stats:write scope.
+func statLinesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := graphql.GetUserFromContext(ctx)
+	if u == nil || graphql.Role(u.Role) != graphql.RoleAdmin {
+		statLinesError(w, http.StatusForbidden, "403: you must be an admin")
+		return
+	}
+
+	if err := graphql.CheckScope(ctx, graphql.ScopeStatsWrite); err != nil {
+		statLinesError(w, http.StatusForbidden, "403: "+err.Error())
+		return
+	}
+
+	precision, err := graphql.ParseStatPrecision(r.URL.Query().Get("precision"))
+	if err != nil {
+		statLinesError(w, http.StatusBadRequest, "400: "+err.Error())
+		return
+	}
+
+	stats, err := graphql.ParseStatLines(http.MaxBytesReader(w, r.Body, maxStatLinesBody), precision)
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		statLinesError(w, http.StatusRequestEntityTooLarge, "413: body is too large")
+		return
+	} else if err != nil {
+		statLinesError(w, http.StatusBadRequest, "400: "+err.Error())
+		return
+	}
+
+	if err := graphql.SaveStats(ctx, stats); err != nil {
+		log.Errorw("could not save stats", "count", len(stats), zap.Error(err))
+		statLinesError(w, http.StatusBadRequest, "400: "+err.Error())
+		return
+	}
+
+	if err := Renderer.JSON(w, http.StatusOK, map[string]int{"saved": len(stats)}); err != nil {
+		log.Errorw("could not render json", zap.Error(err))
+	}
+}
+
+func statLinesError(w http.ResponseWriter, status int, msg string) {
+	if err := Renderer.JSON(w, status, map[string]string{"error": msg}); err != nil {
+		log.Errorw("could not render json", zap.Error(err))
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/icco/graphql"
+	"go.uber.org/zap"
+)
+
+var (
+	// webhookInterval is how often due webhook deliveries are sent. Set with
+	// WEBHOOK_INTERVAL, such as "30s".
+	webhookInterval = 10 * time.Second
+
+	// webhookBatchSize is the most deliveries sent every interval.
+	webhookBatchSize = 50
+
+	webhookClient = &http.Client{Timeout: 15 * time.Second}
+)
+
+func init() {
+	if fromEnv := os.Getenv("WEBHOOK_INTERVAL"); fromEnv != "" {
+		d, err := time.ParseDuration(fromEnv)
+		if err != nil || d <= 0 {
+			log.Warnw("invalid WEBHOOK_INTERVAL, using default", "WEBHOOK_INTERVAL", fromEnv, "default", webhookInterval)
+			return
+		}
+		webhookInterval = d
+	}
+}
+
+// runWebhookDelivery sends webhook deliveries from the outbox until the
+// context is done.
+func runWebhookDelivery(ctx context.Context) {
+	runEvery(ctx, webhookInterval, deliverWebhooksTick)
+}
+
+func deliverWebhooksTick(ctx context.Context) {
+	n, err := graphql.DeliverWebhooks(ctx, webhookClient, webhookBatchSize)
+	if err != nil {
+		log.Errorw("could not deliver webhooks", zap.Error(err))
+	} else if n > 0 {
+		log.Infow("delivered webhooks", "count", n)
+	}
+}
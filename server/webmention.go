@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/icco/graphql"
+	"go.uber.org/zap"
+)
+
+const (
+	// webmentionQueueSize is how many received webmentions can wait to be
+	// verified before new ones are turned away.
+	webmentionQueueSize = 100
+
+	// webmentionTimeout is how long verifying one webmention can take.
+	webmentionTimeout = 30 * time.Second
+)
+
+// webmentionClient fetches pages when receiving and sending webmentions. It
+// only connects to public addresses, since the urls come from strangers.
+var webmentionClient = graphql.NewPublicClient(10 * time.Second)
+
+// webmentionJob is a received webmention waiting to be verified.
+type webmentionJob struct {
+	source string
+	target string
+}
+
+var webmentionJobs = make(chan webmentionJob, webmentionQueueSize)
+
+// webmentionHandler checks a webmention and queues it to be verified, which
+// the spec allows instead of fetching the source during the request.
+func webmentionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderWebmentionError(w, http.StatusBadRequest, "could not parse form")
+		return
+	}
+
+	job := webmentionJob{source: r.PostForm.Get("source"), target: r.PostForm.Get("target")}
+	err := graphql.CheckWebmention(r.Context(), job.source, job.target)
+	var werr *graphql.WebmentionError
+	switch {
+	case errors.As(err, &werr):
+		renderWebmentionError(w, http.StatusBadRequest, werr.Error())
+		return
+	case err != nil:
+		log.Errorw("could not check webmention", zap.Error(err))
+		internalErrorHandler(w, r)
+		return
+	}
+
+	select {
+	case webmentionJobs <- job:
+	default:
+		renderWebmentionError(w, http.StatusServiceUnavailable, "too many webmentions, try again later")
+		return
+	}
+
+	if err := Renderer.JSON(w, http.StatusAccepted, map[string]string{"status": "queued"}); err != nil {
+		log.Errorw("could not render json", zap.Error(err))
+	}
+}
+
+// runWebmentionQueue verifies queued webmentions until the context is done.
+// Failures are only logged, so senders can't use them to probe other hosts.
+func runWebmentionQueue(ctx context.Context) {
+	for {
+		select {
+		case job := <-webmentionJobs:
+			receiveWebmention(ctx, job)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func receiveWebmention(ctx context.Context, job webmentionJob) {
+	ctx, cancel := context.WithTimeout(ctx, webmentionTimeout)
+	defer cancel()
+
+	m, err := graphql.ReceiveWebmention(ctx, webmentionClient, job.source, job.target)
+	switch {
+	case err != nil:
+		log.Warnw("could not receive webmention", "source", job.source, "target", job.target, zap.Error(err))
+	case m == nil:
+		log.Infow("deleted webmention", "source", job.source, "target", job.target)
+	default:
+		log.Infow("received webmention", "source", job.source, "target", job.target)
+	}
+}
+
+func renderWebmentionError(w http.ResponseWriter, status int, msg string) {
+	if err := Renderer.JSON(w, status, map[string]string{"error": msg}); err != nil {
+		log.Errorw("could not render json", zap.Error(err))
+	}
+}
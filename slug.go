@@ -0,0 +1,160 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const maxSlugLength = 80
+
+var (
+	slugInvalidRegex = regexp.MustCompile(`[^a-z0-9]+`)
+	slugNumericRegex = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Slugify turns a title into a lowercase, dash separated slug. Slugs that are
+// only digits are prefixed, so they can never be confused with a post ID.
+func Slugify(title string) string {
+	s := slugInvalidRegex.ReplaceAllString(strings.ToLower(title), "-")
+	s = strings.Trim(s, "-")
+
+	if len(s) > maxSlugLength {
+		s = s[:maxSlugLength]
+		if i := strings.LastIndex(s, "-"); i > 0 {
+			s = s[:i]
+		}
+	}
+
+	if slugNumericRegex.MatchString(s) {
+		s = "post-" + s
+	}
+
+	return s
+}
+
+// maxSlugAttempts is how many slugs uniqueSlug tries before giving up.
+const maxSlugAttempts = 100
+
+// slugCandidate returns the nth slug to try for a post: the slug itself, then
+// the slug with the post's ID, then the slug with the post's ID and a count.
+func slugCandidate(slug, id string, n int) string {
+	switch n {
+	case 0:
+		return slug
+	case 1:
+		return fmt.Sprintf("%s-%s", slug, id)
+	default:
+		return fmt.Sprintf("%s-%s-%d", slug, id, n)
+	}
+}
+
+// uniqueSlug returns a slug for a post that is not used by any other post,
+// either currently or in the slug history.
+func uniqueSlug(ctx context.Context, tx *sql.Tx, id, slug string) (string, error) {
+	if slug == "" {
+		slug = "post"
+	}
+
+	for n := 0; n < maxSlugAttempts; n++ {
+		candidate := slugCandidate(slug, id, n)
+
+		var taken bool
+		row := tx.QueryRowContext(ctx, `
+SELECT EXISTS (SELECT 1 FROM posts WHERE slug = $1 AND id != $2)
+    OR EXISTS (SELECT 1 FROM post_slugs WHERE slug = $1 AND post_id != $2)
+`, candidate, id)
+		if err := row.Scan(&taken); err != nil {
+			return "", fmt.Errorf("check slug: %w", err)
+		}
+
+		if !taken {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("could not find a free slug for %q", slug)
+}
+
+// saveSlug makes sure the post has a unique slug. If the post already had a
+// different slug, the old one is kept in the history so it still resolves.
+func saveSlug(ctx context.Context, tx *sql.Tx, p *Post) error {
+	var old string
+	row := tx.QueryRowContext(ctx, "SELECT COALESCE(slug, '') FROM posts WHERE id = $1", p.ID)
+	if err := row.Scan(&old); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("get slug: %w", err)
+	}
+
+	slug := Slugify(p.Slug)
+	if slug == "" {
+		slug = Slugify(p.Title)
+	}
+
+	slug, err := uniqueSlug(ctx, tx, p.ID, slug)
+	if err != nil {
+		return err
+	}
+	p.Slug = slug
+
+	if old == "" || old == p.Slug {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO post_slugs(slug, post_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (slug) DO UPDATE
+SET (post_id, created_at) = ($2, NOW())
+WHERE post_slugs.slug = $1;
+`, old, p.ID); err != nil {
+		return fmt.Errorf("save old slug: %w", err)
+	}
+
+	return nil
+}
+
+// GetPostBySlug gets a post by its current slug, or by a slug it used to have.
+func GetPostBySlug(ctx context.Context, slug string) (*Post, error) {
+	var id int64
+	row := db.QueryRowContext(ctx, `
+SELECT id FROM posts WHERE slug = $1
+UNION ALL
+SELECT post_id FROM post_slugs WHERE slug = $1
+LIMIT 1
+`, slug)
+	err := row.Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("error with get: %w", err)
+	default:
+		return GetPost(ctx, id)
+	}
+}
+
+// PreviousSlugs returns the slugs this post used to have, newest first.
+func (p *Post) PreviousSlugs(ctx context.Context) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT slug FROM post_slugs WHERE post_id = $1 ORDER BY created_at DESC", p.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slugs := make([]string, 0)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return slugs, nil
+}
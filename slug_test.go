@@ -0,0 +1,55 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]struct {
+		title string
+		want  string
+	}{
+		"simple":      {title: "Hello World", want: "hello-world"},
+		"punctuation": {title: "  What's up, Doc?!  ", want: "what-s-up-doc"},
+		"numeric":     {title: "2019", want: "post-2019"},
+		"unicode":     {title: "Café Olé", want: "caf-ol"},
+		"empty":       {title: "???", want: ""},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := Slugify(tc.title); got != tc.want {
+				t.Errorf("Slugify(%q) = %q, want %q", tc.title, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSlugifyLength(t *testing.T) {
+	got := Slugify(strings.Repeat("word ", 40))
+	if len(got) > maxSlugLength {
+		t.Errorf("expected slug of at most %d characters, got %d", maxSlugLength, len(got))
+	}
+
+	if strings.HasSuffix(got, "-") {
+		t.Errorf("expected slug to not end in a dash, got %q", got)
+	}
+}
+
+func TestSlugCandidate(t *testing.T) {
+	seen := map[string]bool{}
+	for n, want := range []string{"hello", "hello-7", "hello-7-2", "hello-7-3"} {
+		got := slugCandidate("hello", "7", n)
+		if got != want {
+			t.Errorf("slugCandidate(hello, 7, %d) = %q, want %q", n, got, want)
+		}
+
+		if seen[got] {
+			t.Errorf("slugCandidate repeated %q", got)
+		}
+		seen[got] = true
+	}
+}
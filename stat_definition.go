@@ -0,0 +1,163 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// maxStatPrecision is the most decimal places a definition can ask for.
+const maxStatPrecision = 10
+
+// StatDefinition describes what a stat measures, so clients don't need to
+// know that sleep is in hours or weight is in kilograms. Stats without a
+// definition are public.
+type StatDefinition struct {
+	Key         string  `json:"key"`
+	Unit        *string `json:"unit"`
+	Description *string `json:"description"`
+
+	// Precision is how many decimal places values should be shown with.
+	Precision *int     `json:"precision"`
+	Min       *float64 `json:"min"`
+	Max       *float64 `json:"max"`
+
+	// HigherIsBetter is nil if neither direction is better.
+	HigherIsBetter *bool `json:"higher_is_better"`
+
+	// Public stats are shown to everyone. Private stats are only shown to
+	// signed in users.
+	Public   bool      `json:"public"`
+	Created  time.Time `json:"created"`
+	Modified time.Time `json:"modified"`
+}
+
+// Validate checks that the definition makes sense.
+func (d *StatDefinition) Validate() error {
+	if strings.TrimSpace(d.Key) == "" {
+		return fmt.Errorf("stat definition key cannot be empty")
+	}
+
+	if d.Precision != nil && (*d.Precision < 0 || *d.Precision > maxStatPrecision) {
+		return fmt.Errorf("precision must be between 0 and %d", maxStatPrecision)
+	}
+
+	if d.Min != nil && d.Max != nil && *d.Min > *d.Max {
+		return fmt.Errorf("min cannot be more than max")
+	}
+
+	return nil
+}
+
+// Save validates and upserts a definition by its key.
+func (d *StatDefinition) Save(ctx context.Context) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	if d.Created.IsZero() {
+		d.Created = time.Now()
+	}
+	d.Modified = time.Now()
+
+	row := db.QueryRowContext(ctx, `
+INSERT INTO stat_definitions(key, unit, description, precision, min, max, higher_is_better, public, created_at, modified_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (key) DO UPDATE
+SET (unit, description, precision, min, max, higher_is_better, public, modified_at) = ($2, $3, $4, $5, $6, $7, $8, $10)
+RETURNING created_at
+`, d.Key, d.Unit, d.Description, d.Precision, d.Min, d.Max, d.HigherIsBetter, d.Public, d.Created, d.Modified)
+
+	return row.Scan(&d.Created)
+}
+
+// GetStatDefinitions returns every definition, ordered by key. Private
+// definitions are left out unless private is true.
+func GetStatDefinitions(ctx context.Context, private bool) ([]*StatDefinition, error) {
+	return statDefinitionQuery(ctx, `
+SELECT key, unit, description, precision, min, max, higher_is_better, public, created_at, modified_at
+FROM stat_definitions
+WHERE $1 OR public
+ORDER BY key
+`, private)
+}
+
+// DeleteStatDefinition removes the definition of a stat. The stat becomes
+// public.
+func DeleteStatDefinition(ctx context.Context, key string) (*StatDefinition, error) {
+	defs, err := statDefinitionQuery(ctx, `
+DELETE FROM stat_definitions
+WHERE key = $1
+RETURNING key, unit, description, precision, min, max, higher_is_better, public, created_at, modified_at
+`, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(defs) == 0 {
+		return nil, fmt.Errorf("no stat definition %q", key)
+	}
+
+	return defs[0], nil
+}
+
+// CanSeePrivateStats reports whether the request can see private stats.
+func CanSeePrivateStats(ctx context.Context) bool {
+	return GetUserFromContext(ctx) != nil
+}
+
+// hiddenStatKeys returns which of keys are private stats the request can't
+// see.
+func hiddenStatKeys(ctx context.Context, keys []string) (map[string]bool, error) {
+	hidden := map[string]bool{}
+	if CanSeePrivateStats(ctx) || len(keys) == 0 {
+		return hidden, nil
+	}
+
+	var private []string
+	if err := db.QueryRowContext(ctx, `
+SELECT ARRAY(SELECT key FROM stat_definitions WHERE key = ANY($1) AND NOT public)
+`, pq.Array(keys)).Scan(pq.Array(&private)); err != nil {
+		return nil, err
+	}
+
+	for _, k := range private {
+		hidden[k] = true
+	}
+
+	return hidden, nil
+}
+
+func statDefinitionQuery(ctx context.Context, query string, args ...interface{}) ([]*StatDefinition, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	defs := make([]*StatDefinition, 0)
+	for rows.Next() {
+		d := new(StatDefinition)
+		var precision sql.NullInt64
+		if err := rows.Scan(&d.Key, &d.Unit, &d.Description, &precision, &d.Min, &d.Max, &d.HigherIsBetter, &d.Public, &d.Created, &d.Modified); err != nil {
+			return nil, err
+		}
+
+		if precision.Valid {
+			p := int(precision.Int64)
+			d.Precision = &p
+		}
+
+		defs = append(defs, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return defs, nil
+}
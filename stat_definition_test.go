@@ -0,0 +1,38 @@
+package graphql
+
+import (
+	"testing"
+)
+
+func TestStatDefinitionValidate(t *testing.T) {
+	num := func(f float64) *float64 { return &f }
+	places := func(p int) *int { return &p }
+
+	tests := map[string]struct {
+		def   StatDefinition
+		valid bool
+	}{
+		"key only":       {StatDefinition{Key: "sleep"}, true},
+		"everything":     {StatDefinition{Key: "weight", Precision: places(1), Min: num(40), Max: num(120)}, true},
+		"min equals max": {StatDefinition{Key: "flag", Min: num(1), Max: num(1)}, true},
+		"no precision":   {StatDefinition{Key: "steps", Precision: places(0)}, true},
+		"empty key":      {StatDefinition{Key: " "}, false},
+		"min over max":   {StatDefinition{Key: "weight", Min: num(120), Max: num(40)}, false},
+		"negative":       {StatDefinition{Key: "weight", Precision: places(-1)}, false},
+		"too precise":    {StatDefinition{Key: "weight", Precision: places(maxStatPrecision + 1)}, false},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.def.Validate()
+			if tc.valid && err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("Validate() = nil, want error")
+			}
+		})
+	}
+}
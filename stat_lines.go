@@ -0,0 +1,146 @@
+package graphql
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statPrecisions are the units timestamps can be sent in, named like
+// InfluxDB's precision parameter.
+var statPrecisions = map[string]time.Duration{
+	"":   time.Nanosecond,
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+// ParseStatPrecision returns the unit named by s. An empty name means
+// nanoseconds.
+func ParseStatPrecision(s string) (time.Duration, error) {
+	p, ok := statPrecisions[s]
+	if !ok {
+		return 0, fmt.Errorf("invalid precision %q, must be ns, us, ms or s", s)
+	}
+
+	return p, nil
+}
+
+// ParseStatLines reads stats written in a subset of the InfluxDB line
+// protocol. Each line is a measurement, one or more comma separated fields
+// and an optional timestamp in units of precision:
+//
+//	steps value=1000 1697000000000000000
+//	weather temp=18.5,humidity=60i
+//
+// A field named value is saved with the measurement as its key, and other
+// fields are saved as the measurement and field joined with a dot, so the
+// second line saves weather.temp and weather.humidity. Points without a
+// timestamp have a zero When. Blank lines and lines starting with # are
+// skipped. Tags, escapes and string or boolean values are not supported.
+func ParseStatLines(r io.Reader, precision time.Duration) ([]*Stat, error) {
+	var stats []*Stat
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		points, err := parseStatLine(line, precision)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		stats = append(stats, points...)
+		if len(stats) > MaxStatBatch {
+			return nil, fmt.Errorf("at most %d stats can be saved at once", MaxStatBatch)
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func parseStatLine(line string, precision time.Duration) ([]*Stat, error) {
+	parts := strings.Fields(line)
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("must be a measurement, fields and an optional timestamp")
+	}
+
+	measurement := parts[0]
+	if strings.Contains(measurement, ",") {
+		return nil, fmt.Errorf("tags are not supported")
+	}
+
+	var when time.Time
+	if len(parts) == 3 {
+		ts, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", parts[2])
+		}
+
+		if ts > math.MaxInt64/int64(precision) || ts < math.MinInt64/int64(precision) {
+			return nil, fmt.Errorf("timestamp %q is out of range", parts[2])
+		}
+
+		when = time.Unix(0, ts*int64(precision)).UTC()
+	}
+
+	var stats []*Stat
+	for _, field := range strings.Split(parts[1], ",") {
+		name, raw, ok := strings.Cut(field, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid field %q", field)
+		}
+
+		value, err := parseStatValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+
+		key := measurement
+		if name != "value" {
+			key = measurement + "." + name
+		}
+
+		stats = append(stats, &Stat{Key: key, Value: value, When: when})
+	}
+
+	return stats, nil
+}
+
+// parseStatValue parses a float, or an integer ending in i or u.
+func parseStatValue(raw string) (float64, error) {
+	if s, ok := strings.CutSuffix(raw, "i"); ok {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid integer %q", raw)
+		}
+		return float64(i), nil
+	}
+
+	if s, ok := strings.CutSuffix(raw, "u"); ok {
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		return float64(u), nil
+	}
+
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %q", raw)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,104 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStatLines(t *testing.T) {
+	body := `
+# steps from the watch
+steps value=1000 1697000000
+weather temp=18.5,humidity=60i
+counter value=7u 1697000001
+`
+
+	stats, err := ParseStatLines(strings.NewReader(body), time.Second)
+	if err != nil {
+		t.Fatalf("ParseStatLines: %v", err)
+	}
+
+	want := []Stat{
+		{Key: "steps", Value: 1000, When: time.Unix(1697000000, 0).UTC()},
+		{Key: "weather.temp", Value: 18.5},
+		{Key: "weather.humidity", Value: 60},
+		{Key: "counter", Value: 7, When: time.Unix(1697000001, 0).UTC()},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("got %d stats, want %d", len(stats), len(want))
+	}
+	for i, w := range want {
+		if *stats[i] != w {
+			t.Errorf("stat %d = %+v, want %+v", i, *stats[i], w)
+		}
+	}
+}
+
+func TestParseStatLinesErrors(t *testing.T) {
+	tests := map[string]string{
+		"no fields":     "steps",
+		"tags":          "weather,city=sf temp=18",
+		"bad field":     "steps 1000",
+		"bad value":     "steps value=lots",
+		"nan":           "steps value=NaN",
+		"bad integer":   "steps value=1.5i",
+		"bad timestamp": "steps value=1 yesterday",
+		"extra":         "steps value=1 1697000000 more",
+		"overflow":      "steps value=1 9223372036854775807",
+	}
+
+	for name, line := range tests {
+		name, line := name, line // capture range variables
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := ParseStatLines(strings.NewReader("ok value=1\n"+line), time.Second); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+				t.Errorf("ParseStatLines(%q) = %v, want a line 2 error", line, err)
+			}
+		})
+	}
+}
+
+func TestParseStatPrecision(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":   time.Nanosecond,
+		"ns": time.Nanosecond,
+		"us": time.Microsecond,
+		"ms": time.Millisecond,
+		"s":  time.Second,
+	}
+
+	for in, want := range tests {
+		if got, err := ParseStatPrecision(in); err != nil || got != want {
+			t.Errorf("ParseStatPrecision(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+
+	if _, err := ParseStatPrecision("h"); err == nil {
+		t.Error("ParseStatPrecision(h) succeeded, want error")
+	}
+}
+
+func TestStatValidate(t *testing.T) {
+	now := time.Date(2023, 10, 11, 12, 0, 0, 0, time.UTC)
+
+	s := &Stat{Key: "steps", Value: 1}
+	if err := s.validate(now); err != nil || !s.When.Equal(now) {
+		t.Errorf("validate() = %v, When = %v, want nil, %v", err, s.When, now)
+	}
+
+	past := now.Add(-time.Hour)
+	s = &Stat{Key: "steps", Value: 1, When: past}
+	if err := s.validate(now); err != nil || !s.When.Equal(past) {
+		t.Errorf("validate() = %v, When = %v, want nil, %v", err, s.When, past)
+	}
+
+	for _, bad := range []*Stat{
+		{Value: 1},
+		{Key: "steps", Value: 1, When: now.Add(time.Hour)},
+	} {
+		if err := bad.validate(now); err == nil {
+			t.Errorf("validate(%+v) succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// StatRetentionPolicy says how long the history of stats is kept at each
+// resolution. Raw points older than RawDays are rolled up into hourly
+// points, hourly points older than HourlyDays are rolled up into daily
+// points, and daily points older than DailyDays are deleted. Stats without a
+// policy are kept forever.
+type StatRetentionPolicy struct {
+	ID string `json:"id"`
+
+	// Pattern is either a stat key, or a prefix followed by "*", so "*"
+	// alone matches every key. If a key matches more than one policy, an
+	// exact match wins, then the longest prefix.
+	Pattern    string    `json:"pattern"`
+	RawDays    int       `json:"raw_days"`
+	HourlyDays int       `json:"hourly_days"`
+	DailyDays  *int      `json:"daily_days"`
+	Created    time.Time `json:"created"`
+	Modified   time.Time `json:"modified"`
+}
+
+// Matches reports whether the policy covers key.
+func (p *StatRetentionPolicy) Matches(key string) bool {
+	if prefix, ok := strings.CutSuffix(p.Pattern, "*"); ok {
+		return strings.HasPrefix(key, prefix)
+	}
+
+	return key == p.Pattern
+}
+
+// Validate checks that the policy's durations make sense.
+func (p *StatRetentionPolicy) Validate() error {
+	if p.Pattern == "" {
+		return fmt.Errorf("retention policy pattern cannot be empty")
+	}
+
+	if strings.Contains(strings.TrimSuffix(p.Pattern, "*"), "*") {
+		return fmt.Errorf("retention policy pattern %q can only have a * at the end", p.Pattern)
+	}
+
+	if p.RawDays < 1 {
+		return fmt.Errorf("raw points must be kept for at least a day")
+	}
+
+	if p.HourlyDays < p.RawDays {
+		return fmt.Errorf("hourly points must be kept at least as long as raw points")
+	}
+
+	if p.DailyDays != nil && *p.DailyDays < p.HourlyDays {
+		return fmt.Errorf("daily points must be kept at least as long as hourly points")
+	}
+
+	return nil
+}
+
+// Save validates and upserts a policy by its pattern.
+func (p *StatRetentionPolicy) Save(ctx context.Context) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	if p.Created.IsZero() {
+		p.Created = time.Now()
+	}
+	p.Modified = time.Now()
+
+	row := db.QueryRowContext(ctx, `
+INSERT INTO stat_retention_policies(pattern, raw_days, hourly_days, daily_days, created_at, modified_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (pattern) DO UPDATE
+SET (raw_days, hourly_days, daily_days, modified_at) = ($2, $3, $4, $6)
+RETURNING id, created_at
+`, p.Pattern, p.RawDays, p.HourlyDays, p.DailyDays, p.Created, p.Modified)
+
+	return row.Scan(&p.ID, &p.Created)
+}
+
+// GetStatRetentionPolicies returns every policy, ordered by pattern.
+func GetStatRetentionPolicies(ctx context.Context) ([]*StatRetentionPolicy, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT id, pattern, raw_days, hourly_days, daily_days, created_at, modified_at
+FROM stat_retention_policies
+ORDER BY pattern
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]*StatRetentionPolicy, 0)
+	for rows.Next() {
+		p := new(StatRetentionPolicy)
+		var daily sql.NullInt64
+		if err := rows.Scan(&p.ID, &p.Pattern, &p.RawDays, &p.HourlyDays, &daily, &p.Created, &p.Modified); err != nil {
+			return nil, err
+		}
+
+		if daily.Valid {
+			d := int(daily.Int64)
+			p.DailyDays = &d
+		}
+
+		policies = append(policies, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// DeleteStatRetentionPolicy removes the policy with a pattern. Points that
+// have already been rolled up stay rolled up.
+func DeleteStatRetentionPolicy(ctx context.Context, pattern string) (*StatRetentionPolicy, error) {
+	policies, err := GetStatRetentionPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range policies {
+		if p.Pattern != pattern {
+			continue
+		}
+
+		if _, err := db.ExecContext(ctx, "DELETE FROM stat_retention_policies WHERE id = $1", p.ID); err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	}
+
+	return nil, fmt.Errorf("no retention policy %q", pattern)
+}
+
+// policyFor returns the policy that covers key, or nil if none do.
+func policyFor(policies []*StatRetentionPolicy, key string) *StatRetentionPolicy {
+	var best *StatRetentionPolicy
+	for _, p := range policies {
+		if !p.Matches(key) {
+			continue
+		}
+
+		if p.Pattern == key {
+			return p
+		}
+
+		if best == nil || len(p.Pattern) > len(best.Pattern) {
+			best = p
+		}
+	}
+
+	return best
+}
+
+// retentionCutoffs returns the times before which raw points are rolled into
+// hours, hourly points are rolled into days, and daily points are deleted.
+// Cutoffs are on bucket boundaries, so only whole buckets are rolled up. The
+// last cutoff is zero if daily points are kept forever.
+func (p *StatRetentionPolicy) retentionCutoffs(now time.Time) (time.Time, time.Time, time.Time) {
+	raw := StatBucketHour.Truncate(now.AddDate(0, 0, -p.RawDays))
+	hourly := StatBucketDay.Truncate(now.AddDate(0, 0, -p.HourlyDays))
+
+	var daily time.Time
+	if p.DailyDays != nil {
+		daily = StatBucketDay.Truncate(now.AddDate(0, 0, -*p.DailyDays))
+	}
+
+	return raw, hourly, daily
+}
+
+// CompactStats applies the retention policies to every stat. It returns how
+// many raw points were rolled up.
+func CompactStats(ctx context.Context, now time.Time) (int64, error) {
+	policies, err := GetStatRetentionPolicies(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(policies) == 0 {
+		return 0, nil
+	}
+
+	var keys []string
+	if err := db.QueryRowContext(ctx, `
+SELECT ARRAY(SELECT key FROM stats WHERE key IS NOT NULL UNION SELECT key FROM stats_hourly UNION SELECT key FROM stats_daily)
+`).Scan(pq.Array(&keys)); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, key := range keys {
+		p := policyFor(policies, key)
+		if p == nil {
+			continue
+		}
+
+		n, err := compactStat(ctx, key, p, now)
+		if err != nil {
+			return total, fmt.Errorf("compact %q: %w", key, err)
+		}
+		total += n
+	}
+
+	return total, nil
+}
+
+// compactStat rolls up and expires the history of one stat in a single
+// transaction, so readers never see a point twice or not at all.
+func compactStat(ctx context.Context, key string, p *StatRetentionPolicy, now time.Time) (int64, error) {
+	rawCutoff, hourlyCutoff, dailyCutoff := p.retentionCutoffs(now)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO stats_hourly(key, bucket, count, sum, min, max, last, last_at)
+SELECT key, date_trunc('hour', inserted_at, 'UTC'), COUNT(*), SUM(value), MIN(value), MAX(value),
+  (ARRAY_AGG(value ORDER BY inserted_at DESC))[1], MAX(inserted_at)
+FROM stats
+WHERE key = $1 AND inserted_at < $2 AND value IS NOT NULL
+GROUP BY 1, 2
+ON CONFLICT (key, bucket) DO UPDATE
+SET count = stats_hourly.count + excluded.count,
+  sum = stats_hourly.sum + excluded.sum,
+  min = LEAST(stats_hourly.min, excluded.min),
+  max = GREATEST(stats_hourly.max, excluded.max),
+  last = CASE WHEN excluded.last_at >= stats_hourly.last_at THEN excluded.last ELSE stats_hourly.last END,
+  last_at = GREATEST(stats_hourly.last_at, excluded.last_at)
+`, key, rawCutoff); err != nil {
+		return 0, fmt.Errorf("roll up hours: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM stats WHERE key = $1 AND inserted_at < $2", key, rawCutoff)
+	if err != nil {
+		return 0, fmt.Errorf("delete raw points: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO stats_daily(key, bucket, count, sum, min, max, last, last_at)
+SELECT key, date_trunc('day', bucket, 'UTC'), SUM(count), SUM(sum), MIN(min), MAX(max),
+  (ARRAY_AGG(last ORDER BY last_at DESC))[1], MAX(last_at)
+FROM stats_hourly
+WHERE key = $1 AND bucket < $2
+GROUP BY 1, 2
+ON CONFLICT (key, bucket) DO UPDATE
+SET count = stats_daily.count + excluded.count,
+  sum = stats_daily.sum + excluded.sum,
+  min = LEAST(stats_daily.min, excluded.min),
+  max = GREATEST(stats_daily.max, excluded.max),
+  last = CASE WHEN excluded.last_at >= stats_daily.last_at THEN excluded.last ELSE stats_daily.last END,
+  last_at = GREATEST(stats_daily.last_at, excluded.last_at)
+`, key, hourlyCutoff); err != nil {
+		return 0, fmt.Errorf("roll up days: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM stats_hourly WHERE key = $1 AND bucket < $2", key, hourlyCutoff); err != nil {
+		return 0, fmt.Errorf("delete hourly points: %w", err)
+	}
+
+	if !dailyCutoff.IsZero() {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM stats_daily WHERE key = $1 AND bucket < $2", key, dailyCutoff); err != nil {
+			return 0, fmt.Errorf("delete daily points: %w", err)
+		}
+	}
+
+	return n, tx.Commit()
+}
@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyFor(t *testing.T) {
+	policies := []*StatRetentionPolicy{
+		{Pattern: "*"},
+		{Pattern: "weather.*"},
+		{Pattern: "weather.temp.*"},
+		{Pattern: "weather.temp.outside"},
+	}
+
+	tests := map[string]string{
+		"steps":                 "*",
+		"weather.humidity":      "weather.*",
+		"weather.temp.inside":   "weather.temp.*",
+		"weather.temp.outside":  "weather.temp.outside",
+		"weather.temp.outsider": "weather.temp.*",
+	}
+
+	for key, want := range tests {
+		key, want := key, want // capture range variables
+		t.Run(key, func(t *testing.T) {
+			t.Parallel()
+			if got := policyFor(policies, key); got == nil || got.Pattern != want {
+				t.Errorf("policyFor(%q) = %+v, want %q", key, got, want)
+			}
+		})
+	}
+
+	if got := policyFor(policies[1:], "steps"); got != nil {
+		t.Errorf("policyFor(steps) = %+v, want nil", got)
+	}
+}
+
+func TestStatRetentionPolicyValidate(t *testing.T) {
+	days := func(d int) *int { return &d }
+
+	tests := map[string]struct {
+		policy StatRetentionPolicy
+		ok     bool
+	}{
+		"valid":          {policy: StatRetentionPolicy{Pattern: "a.*", RawDays: 7, HourlyDays: 30, DailyDays: days(365)}, ok: true},
+		"forever":        {policy: StatRetentionPolicy{Pattern: "a", RawDays: 1, HourlyDays: 1}, ok: true},
+		"empty pattern":  {policy: StatRetentionPolicy{RawDays: 1, HourlyDays: 1}},
+		"inner star":     {policy: StatRetentionPolicy{Pattern: "a*b", RawDays: 1, HourlyDays: 1}},
+		"no raw":         {policy: StatRetentionPolicy{Pattern: "a", HourlyDays: 1}},
+		"hourly too low": {policy: StatRetentionPolicy{Pattern: "a", RawDays: 7, HourlyDays: 1}},
+		"daily too low":  {policy: StatRetentionPolicy{Pattern: "a", RawDays: 7, HourlyDays: 30, DailyDays: days(7)}},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if err := tc.policy.Validate(); (err == nil) != tc.ok {
+				t.Errorf("Validate() = %v, want ok %t", err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestRetentionCutoffs(t *testing.T) {
+	now := time.Date(2021, 3, 17, 13, 45, 0, 0, time.UTC)
+	daily := 365
+	p := &StatRetentionPolicy{RawDays: 7, HourlyDays: 30, DailyDays: &daily}
+
+	raw, hourly, expire := p.retentionCutoffs(now)
+	if want := time.Date(2021, 3, 10, 13, 0, 0, 0, time.UTC); !raw.Equal(want) {
+		t.Errorf("raw cutoff = %v, want %v", raw, want)
+	}
+
+	if want := time.Date(2021, 2, 15, 0, 0, 0, 0, time.UTC); !hourly.Equal(want) {
+		t.Errorf("hourly cutoff = %v, want %v", hourly, want)
+	}
+
+	if want := time.Date(2020, 3, 17, 0, 0, 0, 0, time.UTC); !expire.Equal(want) {
+		t.Errorf("daily cutoff = %v, want %v", expire, want)
+	}
+
+	p.DailyDays = nil
+	if _, _, expire := p.retentionCutoffs(now); !expire.IsZero() {
+		t.Errorf("daily cutoff = %v, want zero", expire)
+	}
+}
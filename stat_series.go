@@ -0,0 +1,204 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// MaxStatSeriesKeys is the most stats that can be asked for at once.
+	MaxStatSeriesKeys = 20
+
+	// MaxStatSeriesPoints is the most buckets a single series can have.
+	MaxStatSeriesPoints = 2000
+)
+
+// statAggregations are the SQL for each aggregation, over the columns of
+// statRollupHistory. Values are never taken from the request, so they are
+// safe to put in a query.
+var statAggregations = map[StatAggregation]string{
+	StatAggregationAvg:   "SUM(sum) / SUM(count)",
+	StatAggregationMin:   "MIN(min)",
+	StatAggregationMax:   "MAX(max)",
+	StatAggregationSum:   "SUM(sum)",
+	StatAggregationLast:  "(ARRAY_AGG(last ORDER BY last_at DESC))[1]",
+	StatAggregationCount: "SUM(count)::float",
+}
+
+// statRollupHistory is every point of the stats with the keys in $1 from $2
+// to $3, with raw points shaped like rollups, so they can be aggregated
+// together. Rolled up points are at the start of their hour or day, so
+// series with buckets smaller than that put all of a rollup in one bucket.
+const statRollupHistory = `
+SELECT key, inserted_at AS at, 1 AS count, value AS sum, value AS min, value AS max, value AS last, inserted_at AS last_at
+FROM stats WHERE key = ANY($1) AND inserted_at >= $2 AND inserted_at < $3
+UNION ALL
+SELECT key, bucket, count, sum, min, max, last, last_at
+FROM stats_hourly WHERE key = ANY($1) AND bucket >= $2 AND bucket < $3
+UNION ALL
+SELECT key, bucket, count, sum, min, max, last, last_at
+FROM stats_daily WHERE key = ANY($1) AND bucket >= $2 AND bucket < $3
+`
+
+// unit returns the name of the bucket used by Postgres's date_trunc.
+func (b StatBucket) unit() string {
+	switch b {
+	case StatBucketHour:
+		return "hour"
+	case StatBucketDay:
+		return "day"
+	case StatBucketWeek:
+		return "week"
+	default:
+		return "month"
+	}
+}
+
+// Truncate returns the start of the bucket t is in, in UTC. It matches
+// Postgres's date_trunc.
+func (b StatBucket) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch b {
+	case StatBucketHour:
+		return t.Truncate(time.Hour)
+	case StatBucketDay:
+		return day
+	case StatBucketWeek:
+		// Weeks start on Monday.
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Next returns the start of the bucket after the one starting at t.
+func (b StatBucket) Next(t time.Time) time.Time {
+	switch b {
+	case StatBucketHour:
+		return t.Add(time.Hour)
+	case StatBucketDay:
+		return t.AddDate(0, 0, 1)
+	case StatBucketWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+// Buckets returns the start of every bucket from from (inclusive) to to
+// (exclusive). It stops and returns false if there are more than max, so huge
+// ranges are never built.
+func (b StatBucket) Buckets(from, to time.Time, max int) ([]time.Time, bool) {
+	var buckets []time.Time
+	for t := b.Truncate(from); t.Before(to); t = b.Next(t) {
+		if len(buckets) == max {
+			return nil, false
+		}
+		buckets = append(buckets, t)
+	}
+
+	return buckets, true
+}
+
+// GetStatSeries returns the history of each key, aggregated into buckets.
+// Series are returned in the same order as keys.
+func GetStatSeries(ctx context.Context, keys []string, from, to time.Time, bucket StatBucket, agg StatAggregation, fill StatFill) ([]*StatSeries, error) {
+	if len(keys) == 0 {
+		return []*StatSeries{}, nil
+	}
+
+	if len(keys) > MaxStatSeriesKeys {
+		return nil, fmt.Errorf("at most %d keys can be asked for at once", MaxStatSeriesKeys)
+	}
+
+	if !to.After(from) {
+		return nil, fmt.Errorf("to must be after from")
+	}
+
+	if !bucket.IsValid() {
+		return nil, fmt.Errorf("invalid bucket %q", bucket)
+	}
+
+	if !fill.IsValid() {
+		return nil, fmt.Errorf("invalid fill %q", fill)
+	}
+
+	aggSQL, ok := statAggregations[agg]
+	if !ok {
+		return nil, fmt.Errorf("invalid aggregation %q", agg)
+	}
+
+	buckets, ok := bucket.Buckets(from, to, MaxStatSeriesPoints)
+	if !ok {
+		return nil, fmt.Errorf("series can have at most %d points, use a bigger bucket or a smaller range", MaxStatSeriesPoints)
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+SELECT key, date_trunc($4, at, 'UTC') AS bucket, %s
+FROM (%s) h
+GROUP BY key, bucket
+ORDER BY key, bucket
+`, aggSQL, statRollupHistory), pq.Array(keys), from, to, bucket.unit())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := map[string]map[time.Time]float64{}
+	for rows.Next() {
+		var key string
+		var when time.Time
+		var value float64
+		if err := rows.Scan(&key, &when, &value); err != nil {
+			return nil, err
+		}
+
+		if found[key] == nil {
+			found[key] = map[time.Time]float64{}
+		}
+		found[key][when.UTC()] = value
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	series := make([]*StatSeries, len(keys))
+	for i, key := range keys {
+		series[i] = &StatSeries{Key: key, Points: fillStatPoints(buckets, found[key], fill)}
+	}
+
+	return series, nil
+}
+
+// fillStatPoints turns the values of the buckets that had data into points,
+// filling in empty buckets as asked.
+func fillStatPoints(buckets []time.Time, values map[time.Time]float64, fill StatFill) []*StatPoint {
+	points := make([]*StatPoint, 0, len(values))
+	var prev *float64
+	for _, b := range buckets {
+		if v, ok := values[b]; ok {
+			v := v
+			points = append(points, &StatPoint{When: b, Value: &v})
+			prev = &v
+			continue
+		}
+
+		switch fill {
+		case StatFillNull:
+			points = append(points, &StatPoint{When: b})
+		case StatFillZero:
+			zero := 0.0
+			points = append(points, &StatPoint{When: b, Value: &zero})
+		case StatFillPrevious:
+			points = append(points, &StatPoint{When: b, Value: prev})
+		}
+	}
+
+	return points
+}
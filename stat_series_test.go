@@ -0,0 +1,125 @@
+package graphql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStatBucketTruncate(t *testing.T) {
+	// A Wednesday.
+	when := time.Date(2021, 3, 17, 13, 45, 10, 0, time.UTC)
+
+	tests := map[StatBucket]time.Time{
+		StatBucketHour:  time.Date(2021, 3, 17, 13, 0, 0, 0, time.UTC),
+		StatBucketDay:   time.Date(2021, 3, 17, 0, 0, 0, 0, time.UTC),
+		StatBucketWeek:  time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC),
+		StatBucketMonth: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for bucket, want := range tests {
+		bucket, want := bucket, want // capture range variables
+		t.Run(bucket.String(), func(t *testing.T) {
+			t.Parallel()
+			if got := bucket.Truncate(when); !got.Equal(want) {
+				t.Errorf("Truncate() = %v, want %v", got, want)
+			}
+
+			// Times in other zones are bucketed in UTC.
+			if got := bucket.Truncate(when.In(time.FixedZone("PST", -8*60*60))); !got.Equal(want) {
+				t.Errorf("Truncate() in PST = %v, want %v", got, want)
+			}
+		})
+	}
+
+	sunday := time.Date(2021, 3, 21, 23, 0, 0, 0, time.UTC)
+	if got, want := StatBucketWeek.Truncate(sunday), time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Truncate(sunday) = %v, want %v", got, want)
+	}
+}
+
+func TestStatBucketBuckets(t *testing.T) {
+	from := time.Date(2021, 1, 31, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	got, ok := StatBucketMonth.Buckets(from, to, MaxStatSeriesPoints)
+	if !ok {
+		t.Fatal("Buckets() went over the limit")
+	}
+	want := []time.Time{
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Buckets() = %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("Buckets()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if got, ok := StatBucketHour.Buckets(from, from.Add(24*time.Hour), 24); !ok || len(got) != 24 {
+		t.Errorf("Buckets() has %d hours, want 24", len(got))
+	}
+
+	if _, ok := StatBucketHour.Buckets(from, from.Add(25*time.Hour), 24); ok {
+		t.Error("Buckets() with 25 hours and a limit of 24 succeeded")
+	}
+}
+
+func TestGetStatSeriesTooManyPoints(t *testing.T) {
+	from := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	done := make(chan error)
+	go func() {
+		_, err := GetStatSeries(context.Background(), []string{"steps"}, from, to, StatBucketHour, StatAggregationAvg, StatFillNone)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("GetStatSeries() succeeded, want a too many points error")
+		}
+	case <-time.After(time.Second):
+		t.Error("GetStatSeries() took too long to reject a huge range")
+	}
+}
+
+func TestFillStatPoints(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	buckets := []time.Time{day(1), day(2), day(3), day(4)}
+	values := map[time.Time]float64{day(2): 5, day(4): 7}
+
+	tests := map[StatFill][]interface{}{
+		StatFillNone:     {5.0, 7.0},
+		StatFillNull:     {nil, 5.0, nil, 7.0},
+		StatFillZero:     {0.0, 5.0, 0.0, 7.0},
+		StatFillPrevious: {nil, 5.0, 5.0, 7.0},
+	}
+
+	for fill, want := range tests {
+		fill, want := fill, want // capture range variables
+		t.Run(fill.String(), func(t *testing.T) {
+			t.Parallel()
+			got := fillStatPoints(buckets, values, fill)
+			if len(got) != len(want) {
+				t.Fatalf("got %d points, want %d", len(got), len(want))
+			}
+
+			for i, p := range got {
+				switch {
+				case want[i] == nil && p.Value != nil:
+					t.Errorf("point %d = %v, want null", i, *p.Value)
+				case want[i] != nil && (p.Value == nil || *p.Value != want[i].(float64)):
+					t.Errorf("point %d = %v, want %v", i, p.Value, want[i])
+				}
+			}
+		})
+	}
+}
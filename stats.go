@@ -3,39 +3,170 @@ package graphql
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
+
+	"github.com/lib/pq"
+)
+
+// Stat is a value of a stat at a point in time.
+type Stat struct {
+	Key   string    `json:"key"`
+	Value float64   `json:"value"`
+	When  time.Time `json:"when"`
+}
+
+// Definition returns what the stat measures, or nil if it has no definition.
+func (s *Stat) Definition(ctx context.Context) (*StatDefinition, error) {
+	return LoadStatDefinition(ctx, s.Key)
+}
+
+// statHistory is every point of the stats with the keys in $1, at the finest
+// resolution kept. Rolled up points are averaged, and their time is the start
+// of their hour or day. The columns are key, value and inserted_at.
+const statHistory = `
+SELECT key, value, inserted_at FROM stats WHERE key = ANY($1)
+UNION ALL
+SELECT key, sum / count, bucket FROM stats_hourly WHERE key = ANY($1)
+UNION ALL
+SELECT key, sum / count, bucket FROM stats_daily WHERE key = ANY($1)
+`
+
+// statPagedHistory is statHistory with an id column, so it can be paged
+// through by (inserted_at, id). Raw points keep their ID, which is always
+// positive, and rolled up points get 0 if hourly or -1 if daily. A stat has
+// at most one rollup of each kind per bucket, so the pair stays unique.
+const statPagedHistory = `
+SELECT id, key, value, inserted_at FROM stats WHERE key = ANY($1)
+UNION ALL
+SELECT 0, key, sum / count, bucket FROM stats_hourly WHERE key = ANY($1)
+UNION ALL
+SELECT -1, key, sum / count, bucket FROM stats_daily WHERE key = ANY($1)
+`
+
+const (
+	// MaxStatBatch is the most points that can be saved at once.
+	MaxStatBatch = 10000
+
+	// maxStatSkew is how far in the future a point's time can be, to allow
+	// for clocks that are a little off.
+	maxStatSkew = 5 * time.Minute
 )
 
-// Save upserts a stat.
+// Save upserts a stat. If When is zero, it is set to now.
 func (s *Stat) Save(ctx context.Context) error {
+	return SaveStats(ctx, []*Stat{s})
+}
+
+// validate checks a point before it is saved, and sets its time to now if it
+// doesn't have one.
+func (s *Stat) validate(now time.Time) error {
 	if s.Key == "" {
 		return fmt.Errorf("Empty key not allowed")
 	}
 
-	s.When = time.Now()
+	if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+		return fmt.Errorf("stat %q must have a finite value", s.Key)
+	}
 
-	if _, err := db.ExecContext(
-		ctx,
-		`INSERT INTO stats(key, value, inserted_at) VALUES ($1, $2, $3)`,
-		s.Key,
-		s.Value,
-		s.When,
-	); err != nil {
-		return err
+	if s.When.IsZero() {
+		s.When = now
+	}
+
+	if s.When.After(now.Add(maxStatSkew)) {
+		return fmt.Errorf("stat %q is in the future", s.Key)
 	}
 
 	return nil
 }
 
-// GetStats returns the limit of the most recently updated stats.
-func GetStats(ctx context.Context, limit int) ([]*Stat, error) {
+// SaveStats saves many points in one transaction with a COPY, so either all
+// of them are saved or none are. Subscribers and webhooks are sent the newest
+// point of each key, rather than every point.
+func SaveStats(ctx context.Context, stats []*Stat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	if len(stats) > MaxStatBatch {
+		return fmt.Errorf("at most %d stats can be saved at once", MaxStatBatch)
+	}
+
+	now := time.Now()
+	newest := map[string]*Stat{}
+	var keys []string
+	for _, s := range stats {
+		if err := s.validate(now); err != nil {
+			return err
+		}
+
+		if n, ok := newest[s.Key]; !ok {
+			keys = append(keys, s.Key)
+			newest[s.Key] = s
+		} else if !s.When.Before(n.When) {
+			newest[s.Key] = s
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("stats", "key", "value", "inserted_at"))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range stats {
+		if _, err := stmt.ExecContext(ctx, s.Key, s.Value, s.When); err != nil {
+			return err
+		}
+	}
+
+	// An Exec with no arguments flushes the COPY.
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return err
+	}
+
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := notify(ctx, tx, StatUpdatedChannel, newest[k]); err != nil {
+			return err
+		}
+
+		if err := enqueueEvent(ctx, tx, WebhookEventStatUpserted, newest[k]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetStats returns the limit of the most recently updated stats. Stats whose
+// raw points have all been rolled up return their last value. Private stats
+// are left out unless private is true.
+func GetStats(ctx context.Context, limit int, private bool) ([]*Stat, error) {
 	rows, err := db.QueryContext(
 		ctx,
 		`SELECT DISTINCT ON (key) key, value, inserted_at
-    FROM stats
+    FROM (
+      SELECT key, value, inserted_at FROM stats
+      UNION ALL
+      SELECT key, last, last_at FROM stats_hourly
+      UNION ALL
+      SELECT key, last, last_at FROM stats_daily
+    ) s
+    WHERE $2 OR key NOT IN (SELECT key FROM stat_definitions WHERE NOT public)
     ORDER by key, inserted_at DESC
     LIMIT $1`,
-		limit)
+		limit,
+		private)
 	if err != nil {
 		return nil, err
 	}
@@ -56,16 +187,16 @@ func GetStats(ctx context.Context, limit int) ([]*Stat, error) {
 	return stats, nil
 }
 
-// GetStat returns the history of a stat
+// GetStat returns the history of a stat. Old points that have been rolled up
+// by a retention policy are returned as hourly or daily averages.
 func GetStat(ctx context.Context, key string, limit int, offset int) ([]*Stat, error) {
 	rows, err := db.QueryContext(
 		ctx,
 		`SELECT key, value, inserted_at
-    FROM stats
-    WHERE key = $1
+    FROM (`+statHistory+`) s
     ORDER by inserted_at DESC
     LIMIT $2 OFFSET $3`,
-		key,
+		pq.Array([]string{key}),
 		limit,
 		offset)
 	if err != nil {
@@ -87,3 +218,59 @@ func GetStat(ctx context.Context, key string, limit int, offset int) ([]*Stat, e
 	}
 	return stats, nil
 }
+
+// GetStatConnection returns a page of the history of a stat, newest first,
+// starting after the cursor. Like GetStat, it includes old points that have
+// been rolled up.
+func GetStatConnection(ctx context.Context, key string, first int, after *Cursor) (*StatConnection, error) {
+	t, id, err := after.intArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT id, key, value, inserted_at
+    FROM (`+statPagedHistory+`) s
+    WHERE $2::timestamptz IS NULL OR (inserted_at, id) < ($2::timestamptz, $3::bigint)
+    ORDER by inserted_at DESC, id DESC
+    LIMIT $4`,
+		pq.Array([]string{key}),
+		t,
+		id,
+		first+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conn := &StatConnection{Edges: []*StatEdge{}}
+	var last *Cursor
+	count := 0
+	for rows.Next() {
+		var statID string
+		stat := new(Stat)
+		if err := rows.Scan(&statID, &stat.Key, &stat.Value, &stat.When); err != nil {
+			return nil, err
+		}
+
+		count++
+		if count > first {
+			break
+		}
+
+		last = &Cursor{Time: stat.When, ID: statID}
+		conn.Edges = append(conn.Edges, &StatEdge{Cursor: last.String(), Node: stat})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	conn.PageInfo = newPageInfo(count, first, last)
+
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+statPagedHistory+") s", pq.Array([]string{key})).Scan(&conn.TotalCount); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,99 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.41
+
+import (
+	"context"
+	"time"
+)
+
+// SetStatDefinition is the resolver for the setStatDefinition field.
+func (r *mutationResolver) SetStatDefinition(ctx context.Context, input EditStatDefinition) (*StatDefinition, error) {
+	d := &StatDefinition{
+		Key:            input.Key,
+		Unit:           input.Unit,
+		Description:    input.Description,
+		Precision:      input.Precision,
+		Min:            input.Min,
+		Max:            input.Max,
+		HigherIsBetter: input.HigherIsBetter,
+		Public:         true,
+	}
+
+	if input.Public != nil {
+		d.Public = *input.Public
+	}
+
+	if err := d.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// DeleteStatDefinition is the resolver for the deleteStatDefinition field.
+func (r *mutationResolver) DeleteStatDefinition(ctx context.Context, key string) (*StatDefinition, error) {
+	return DeleteStatDefinition(ctx, key)
+}
+
+// SetStatRetentionPolicy is the resolver for the setStatRetentionPolicy field.
+func (r *mutationResolver) SetStatRetentionPolicy(ctx context.Context, input EditStatRetentionPolicy) (*StatRetentionPolicy, error) {
+	p := &StatRetentionPolicy{
+		Pattern:    input.Pattern,
+		RawDays:    input.RawDays,
+		HourlyDays: input.HourlyDays,
+		DailyDays:  input.DailyDays,
+	}
+
+	if err := p.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// DeleteStatRetentionPolicy is the resolver for the deleteStatRetentionPolicy field.
+func (r *mutationResolver) DeleteStatRetentionPolicy(ctx context.Context, pattern string) (*StatRetentionPolicy, error) {
+	return DeleteStatRetentionPolicy(ctx, pattern)
+}
+
+// StatSeries is the resolver for the statSeries field.
+func (r *queryResolver) StatSeries(ctx context.Context, keys []string, from time.Time, to time.Time, bucket StatBucket, agg StatAggregation, fill *StatFill) ([]*StatSeries, error) {
+	f := StatFillNone
+	if fill != nil {
+		f = *fill
+	}
+
+	series, err := GetStatSeries(ctx, keys, from, to, bucket, agg, f)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden, err := hiddenStatKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Private stats look like stats with no points. GetStatSeries already
+	// checked that the buckets fit.
+	buckets, _ := bucket.Buckets(from, to, MaxStatSeriesPoints)
+	for _, s := range series {
+		if hidden[s.Key] {
+			s.Points = fillStatPoints(buckets, nil, f)
+		}
+	}
+
+	return series, nil
+}
+
+// StatDefinitions is the resolver for the statDefinitions field.
+func (r *queryResolver) StatDefinitions(ctx context.Context) ([]*StatDefinition, error) {
+	return GetStatDefinitions(ctx, CanSeePrivateStats(ctx))
+}
+
+// StatRetentionPolicies is the resolver for the statRetentionPolicies field.
+func (r *queryResolver) StatRetentionPolicies(ctx context.Context) ([]*StatRetentionPolicy, error) {
+	return GetStatRetentionPolicies(ctx)
+}
@@ -0,0 +1,56 @@
+package graphql
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetStatConnectionIncludesRollups(t *testing.T) {
+	m := mockDB(t)
+	now := time.Date(2023, 10, 11, 12, 30, 0, 0, time.UTC)
+	hour := now.Add(-48 * time.Hour).Truncate(time.Hour)
+	day := now.AddDate(0, 0, -60).Truncate(24 * time.Hour)
+
+	m.ExpectQuery(`(?s)FROM stats WHERE.*FROM stats_hourly WHERE.*FROM stats_daily WHERE.*\(inserted_at, id\) <`).
+		WithArgs(sqlmock.AnyArg(), nil, nil, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "value", "inserted_at"}).
+			AddRow("9", "steps", 100, now).
+			AddRow("0", "steps", 50, hour).
+			AddRow("-1", "steps", 25, day))
+	m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM (") + `(?s).*stats_hourly.*stats_daily`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	conn, err := GetStatConnection(context.Background(), "steps", 3, nil)
+	if err != nil {
+		t.Fatalf("GetStatConnection() = %v", err)
+	}
+
+	if len(conn.Edges) != 3 || conn.TotalCount != 3 || conn.PageInfo.HasNextPage {
+		t.Fatalf("GetStatConnection() = %d edges, total %d, next %t, want 3, 3, false", len(conn.Edges), conn.TotalCount, conn.PageInfo.HasNextPage)
+	}
+
+	c, err := ParseCursor(*conn.PageInfo.EndCursor)
+	if err != nil {
+		t.Fatalf("ParseCursor() = %v", err)
+	}
+	if !c.Time.Equal(day) || c.ID != "-1" {
+		t.Errorf("end cursor = %+v, want the daily rollup", c)
+	}
+
+	// The cursor of a rollup can be used to get the next page.
+	m.ExpectQuery(`(?s)stats_daily.*\(inserted_at, id\) <`).
+		WithArgs(sqlmock.AnyArg(), c.Time, "-1", 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "value", "inserted_at"}))
+	m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM (")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	if _, err := GetStatConnection(context.Background(), "steps", 3, c); err != nil {
+		t.Errorf("GetStatConnection() after a rollup = %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.41
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.uber.org/zap"
+)
+
+// CommentAdded is the resolver for the commentAdded field.
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID *string) (<-chan *Comment, error) {
+	ch := make(chan *Comment, 1)
+	events := Subscribe(ctx, CommentAddedChannel)
+
+	go func() {
+		defer close(ch)
+		for payload := range events {
+			var n struct {
+				ID     string `json:"id"`
+				PostID string `json:"post_id"`
+			}
+			if err := json.Unmarshal([]byte(payload), &n); err != nil {
+				log.Errorw("could not parse comment notification", "payload", payload, zap.Error(err))
+				continue
+			}
+
+			if postID != nil && *postID != n.PostID {
+				continue
+			}
+
+			c, err := GetComment(ctx, n.ID)
+			if err != nil {
+				log.Errorw("could not get added comment", "id", n.ID, zap.Error(err))
+				continue
+			}
+
+			select {
+			case ch <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// PostPublished is the resolver for the postPublished field.
+func (r *subscriptionResolver) PostPublished(ctx context.Context) (<-chan *Post, error) {
+	ch := make(chan *Post, 1)
+	events := Subscribe(ctx, PostPublishedChannel)
+
+	go func() {
+		defer close(ch)
+		for payload := range events {
+			var n struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal([]byte(payload), &n); err != nil {
+				log.Errorw("could not parse post notification", "payload", payload, zap.Error(err))
+				continue
+			}
+
+			p, err := GetPostString(ctx, n.ID)
+			if err != nil || p == nil {
+				log.Errorw("could not get published post", "id", n.ID, zap.Error(err))
+				continue
+			}
+
+			select {
+			case ch <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// StatUpdated is the resolver for the statUpdated field.
+func (r *subscriptionResolver) StatUpdated(ctx context.Context, key *string) (<-chan *Stat, error) {
+	ch := make(chan *Stat, 1)
+	events := Subscribe(ctx, StatUpdatedChannel)
+
+	go func() {
+		defer close(ch)
+		for payload := range events {
+			s := new(Stat)
+			if err := json.Unmarshal([]byte(payload), s); err != nil {
+				log.Errorw("could not parse stat notification", "payload", payload, zap.Error(err))
+				continue
+			}
+
+			if key != nil && *key != s.Key {
+				continue
+			}
+
+			hidden, err := hiddenStatKeys(ctx, []string{s.Key})
+			if err != nil {
+				log.Errorw("could not check if stat is private", "key", s.Key, zap.Error(err))
+				continue
+			}
+
+			if hidden[s.Key] {
+				continue
+			}
+
+			select {
+			case ch <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+type subscriptionResolver struct{ *Resolver }
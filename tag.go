@@ -0,0 +1,383 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Tag is a hashtag used on posts and links. Aliases are other names for the
+// tag, which are rewritten to the tag's name whenever a post or link is saved.
+type Tag struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Aliases     []string  `json:"aliases"`
+	Created     time.Time `json:"created"`
+	Modified    time.Time `json:"modified"`
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// CanonicalTags replaces any aliases in a list of tags with the name of the
+// tag they are an alias of. The result is sorted and has no duplicates.
+func CanonicalTags(ctx context.Context, q queryer, tags []string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, "SELECT alias, tag FROM tag_aliases WHERE alias = ANY($1)", pq.Array(tags))
+	if err != nil {
+		return nil, fmt.Errorf("get tag aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := map[string]string{}
+	for rows.Next() {
+		var alias, tag string
+		if err := rows.Scan(&alias, &tag); err != nil {
+			return nil, err
+		}
+		aliases[alias] = tag
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return canonicalize(tags, aliases), nil
+}
+
+func canonicalize(tags []string, aliases map[string]string) []string {
+	seen := map[string]bool{}
+	ret := []string{}
+	for _, t := range tags {
+		if alias, ok := aliases[t]; ok {
+			t = alias
+		}
+
+		if !seen[t] {
+			seen[t] = true
+			ret = append(ret, t)
+		}
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+// GetTag gets a tag by its name or one of its aliases. Tags that are used but
+// have never been described are returned without a description. Unknown tags
+// are nil.
+func GetTag(ctx context.Context, name string) (*Tag, error) {
+	tags, err := tagQuery(ctx, `
+WITH name AS (
+  SELECT COALESCE((SELECT tag FROM tag_aliases WHERE alias = $1), $1) AS n
+)
+SELECT n, COALESCE(description, ''), created_at, modified_at
+FROM name
+LEFT JOIN tags ON tags.name = n
+WHERE tags.name IS NOT NULL
+   OR EXISTS (SELECT 1 FROM posts WHERE tags @> ARRAY[n])
+   OR EXISTS (SELECT 1 FROM links WHERE tags @> ARRAY[n])
+`, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	return tags[0], nil
+}
+
+// AllTagDetails returns every described or used tag, most used first.
+func AllTagDetails(ctx context.Context, limit, offset int) ([]*Tag, error) {
+	return tagQuery(ctx, `
+WITH names AS (
+  SELECT name FROM tags
+  UNION
+  SELECT UNNEST(tags) FROM posts
+  UNION
+  SELECT UNNEST(tags) FROM links
+)
+SELECT names.name, COALESCE(description, ''), created_at, modified_at
+FROM names
+LEFT JOIN tags ON tags.name = names.name
+ORDER BY (SELECT COUNT(*) FROM posts WHERE tags @> ARRAY[names.name]) DESC, names.name
+LIMIT $1 OFFSET $2
+`, limit, offset)
+}
+
+func tagQuery(ctx context.Context, query string, args ...interface{}) ([]*Tag, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]*Tag, 0)
+	for rows.Next() {
+		t := new(Tag)
+		var created, modified sql.NullTime
+		if err := rows.Scan(&t.Name, &t.Description, &created, &modified); err != nil {
+			return nil, err
+		}
+		t.Created = created.Time
+		t.Modified = modified.Time
+		tags = append(tags, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadAliases(ctx, tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// loadAliases sets the aliases of every tag with one query.
+func loadAliases(ctx context.Context, tags []*Tag) error {
+	byName := map[string]*Tag{}
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t.Aliases = []string{}
+		byName[t.Name] = t
+		names = append(names, t.Name)
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT tag, alias FROM tag_aliases WHERE tag = ANY($1) ORDER BY tag, alias", pq.Array(names))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag, alias string
+		if err := rows.Scan(&tag, &alias); err != nil {
+			return err
+		}
+		if t, ok := byName[tag]; ok {
+			t.Aliases = append(t.Aliases, alias)
+		}
+	}
+
+	return rows.Err()
+}
+
+// PostCount returns the number of published posts with this tag.
+func (t *Tag) PostCount(ctx context.Context) (int, error) {
+	var count int
+	row := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts WHERE tags @> ARRAY[$1] AND draft = false AND date <= NOW()", t.Name)
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Posts returns the published posts with this tag, newest first.
+func (t *Tag) Posts(ctx context.Context, input *Limit) ([]*Post, error) {
+	limit, offset := ParseLimit(input, 10, 0)
+
+	return postQuery(ctx, `
+SELECT id, title, content, date, created_at, modified_at, tags, draft, slug
+FROM posts
+WHERE tags @> ARRAY[$1]
+  AND draft = false
+  AND date <= NOW()
+ORDER BY date DESC
+LIMIT $2 OFFSET $3
+`, t.Name, limit, offset)
+}
+
+// Links returns the links with this tag, newest first.
+func (t *Tag) Links(ctx context.Context, input *Limit) ([]*Link, error) {
+	limit, offset := ParseLimit(input, 10, 0)
+
+	rows, err := db.QueryContext(ctx, `
+SELECT id, title, uri, description, created, modified_at, tags
+FROM links
+WHERE tags @> ARRAY[$1]
+ORDER BY created DESC
+LIMIT $2 OFFSET $3
+`, t.Name, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]*Link, 0)
+	for rows.Next() {
+		link := new(Link)
+		if err := rows.Scan(&link.ID, &link.Title, &link.URI, &link.Description, &link.Created, &link.Modified, pq.Array(&link.Tags)); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// normalizeTag lowercases a tag and strips a leading #.
+func normalizeTag(t string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(t), "#"))
+}
+
+// UpdateTag sets the description and aliases of a tag. Posts and links using a
+// new alias are rewritten to use the tag instead. If aliases is nil, the
+// existing aliases are kept.
+func UpdateTag(ctx context.Context, name, description string, aliases []string) (*Tag, error) {
+	name = normalizeTag(name)
+	if name == "" {
+		return nil, fmt.Errorf("tag name cannot be empty")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO tags(name, description, created_at, modified_at)
+VALUES ($1, $2, NOW(), NOW())
+ON CONFLICT (name) DO UPDATE
+SET (description, modified_at) = ($2, NOW())
+WHERE tags.name = $1;
+`, name, description); err != nil {
+		return nil, fmt.Errorf("save tag: %w", err)
+	}
+
+	if aliases != nil {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM tag_aliases WHERE tag = $1", name); err != nil {
+			return nil, fmt.Errorf("clear aliases: %w", err)
+		}
+
+		var from []string
+		for _, a := range aliases {
+			if a = normalizeTag(a); a != "" && a != name {
+				from = append(from, a)
+			}
+		}
+
+		if err := mergeTags(ctx, tx, from, name); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return GetTag(ctx, name)
+}
+
+// RenameTag renames a tag on every post and link. The old name becomes an
+// alias, so posts saved with it later get the new name.
+func RenameTag(ctx context.Context, from, to string) (*Tag, error) {
+	return MergeTags(ctx, []string{from}, to)
+}
+
+// MergeTags replaces a set of tags with another on every post and link, all
+// at once. The merged tags become aliases of the tag they were merged into,
+// and so do their aliases.
+func MergeTags(ctx context.Context, from []string, into string) (*Tag, error) {
+	into = normalizeTag(into)
+	if into == "" {
+		return nil, fmt.Errorf("tag name cannot be empty")
+	}
+
+	var names []string
+	for _, f := range from {
+		if f = normalizeTag(f); f != "" && f != into {
+			names = append(names, f)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no tags to merge into %q", into)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The new name can no longer be an alias of something else.
+	if _, err := tx.ExecContext(ctx, "DELETE FROM tag_aliases WHERE alias = $1", into); err != nil {
+		return nil, fmt.Errorf("remove alias: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO tags(name, description, created_at, modified_at)
+VALUES ($1, COALESCE((SELECT description FROM tags WHERE name = ANY($2) AND description != '' LIMIT 1), ''), NOW(), NOW())
+ON CONFLICT (name) DO NOTHING;
+`, into, pq.Array(names)); err != nil {
+		return nil, fmt.Errorf("save tag: %w", err)
+	}
+
+	if err := mergeTags(ctx, tx, names, into); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return GetTag(ctx, into)
+}
+
+// mergeTags does the work of MergeTags inside a transaction. The tag being
+// merged into must already exist.
+func mergeTags(ctx context.Context, tx *sql.Tx, from []string, into string) error {
+	if len(from) == 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE tag_aliases SET tag = $2 WHERE tag = ANY($1)", pq.Array(from), into); err != nil {
+		return fmt.Errorf("move aliases: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM tags WHERE name = ANY($1)", pq.Array(from)); err != nil {
+		return fmt.Errorf("remove merged tags: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO tag_aliases(alias, tag)
+SELECT UNNEST($1::text[]), $2
+ON CONFLICT (alias) DO UPDATE
+SET tag = $2;
+`, pq.Array(from), into); err != nil {
+		return fmt.Errorf("add aliases: %w", err)
+	}
+
+	for _, table := range []string{"posts", "links"} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+UPDATE %s
+SET tags = ARRAY(SELECT DISTINCT CASE WHEN t = ANY($1) THEN $2 ELSE t END FROM UNNEST(tags) t ORDER BY 1)
+WHERE tags && $1::text[]
+`, table), pq.Array(from), into); err != nil {
+			return fmt.Errorf("rewrite %s tags: %w", table, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package graphql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCanonicalize(t *testing.T) {
+	aliases := map[string]string{"hackerschool": "recursecenter"}
+	tests := map[string]struct {
+		tags []string
+		want []string
+	}{
+		"no aliases": {tags: []string{"go", "art"}, want: []string{"art", "go"}},
+		"alias":      {tags: []string{"hackerschool", "go"}, want: []string{"go", "recursecenter"}},
+		"duplicate":  {tags: []string{"hackerschool", "recursecenter"}, want: []string{"recursecenter"}},
+		"empty":      {tags: nil, want: []string{}},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := canonicalize(tc.tags, aliases); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("canonicalize(%v) = %v, want %v", tc.tags, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPostsByTagOnlyPublished(t *testing.T) {
+	m := mockDB(t)
+	now := time.Now()
+	columns := []string{"id", "title", "content", "date", "created_at", "modified_at", "tags", "draft", "slug"}
+
+	// The future post is left out by the date check, so only the past one
+	// comes back.
+	m.ExpectQuery(`(?s)draft = false\s+AND date <= NOW\(\)\s+ORDER BY date DESC\s+LIMIT \$2`).
+		WithArgs("go", 5).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("1", "Past", "", now.Add(-time.Hour), now, now, "{go}", false, "past"))
+
+	posts, err := PostsByTag(context.Background(), "go", 5)
+	if err != nil {
+		t.Fatalf("PostsByTag() = %v", err)
+	}
+
+	if len(posts) != 1 || posts[0].ID != "1" {
+		t.Errorf("PostsByTag() = %v, want only the past post", posts)
+	}
+}
+
+func TestAllTagDetailsLoadsAliasesAtOnce(t *testing.T) {
+	m := mockDB(t)
+	now := time.Now()
+
+	m.ExpectQuery(`(?s)WITH names AS`).
+		WithArgs(10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "description", "created_at", "modified_at"}).
+			AddRow("go", "", now, now).
+			AddRow("recursecenter", "", now, now).
+			AddRow("art", "", nil, nil))
+	m.ExpectQuery(`SELECT tag, alias FROM tag_aliases WHERE tag = ANY\(\$1\)`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"tag", "alias"}).
+			AddRow("go", "golang").
+			AddRow("recursecenter", "hackerschool").
+			AddRow("recursecenter", "rc"))
+
+	tags, err := AllTagDetails(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatalf("AllTagDetails() = %v", err)
+	}
+
+	want := map[string][]string{
+		"go":            {"golang"},
+		"recursecenter": {"hackerschool", "rc"},
+		"art":           {},
+	}
+	if len(tags) != len(want) {
+		t.Fatalf("AllTagDetails() returned %d tags, want %d", len(tags), len(want))
+	}
+	for _, tag := range tags {
+		if !reflect.DeepEqual(tag.Aliases, want[tag.Name]) {
+			t.Errorf("%s aliases = %v, want %v", tag.Name, tag.Aliases, want[tag.Name])
+		}
+	}
+}
@@ -31,15 +31,9 @@ type TwitterURL struct {
 	ModifiedAt time.Time
 }
 
-// Tweets returns an array of tweets.
+// Tweets returns an array of tweets. Tweets we have not archived are nil.
 func (tu *TwitterURL) Tweets(ctx context.Context) ([]*Tweet, error) {
-	tweets := make([]*Tweet, len(tu.TweetIDs))
-	for i, id := range tu.TweetIDs {
-		t, _ := GetTweet(ctx, id)
-		tweets[i] = t
-	}
-
-	return tweets, nil
+	return LoadTweets(ctx, tu.TweetIDs)
 }
 
 // IsLinkable exists to show that this method implements the Linkable type in
@@ -88,6 +82,19 @@ WHERE tweets.id = $1;
 // graphql.
 func (t *Tweet) IsLinkable() {}
 
+// IsSearchable exists to show that this method implements the Searchable
+// type in graphql.
+func (t *Tweet) IsSearchable() {}
+
+// Summary returns the text of the tweet.
+func (t *Tweet) Summary() string {
+	return t.Text
+}
+
+func (t *Tweet) GetSummary() string {
+	return t.Summary()
+}
+
 // GetTweet returns a single tweet by id.
 func GetTweet(ctx context.Context, id string) (*Tweet, error) {
 	var tweet Tweet
@@ -187,3 +194,71 @@ func GetTweetsByScreenName(ctx context.Context, screenName string, limit, offset
 
 	return tweets, nil
 }
+
+// GetTweetsConnection returns a page of tweets in reverse order of when they
+// were archived, starting after the cursor. Cursors hold the tweet's
+// internal_id, so tweets inserted while paging do not shift later pages.
+func GetTweetsConnection(ctx context.Context, first int, after *Cursor) (*TweetConnection, error) {
+	_, id, err := after.intArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+  SELECT internal_id, id, text, hashtags, symbols, user_mentions, urls, screen_name, favorites, retweets, posted
+  FROM tweets
+  WHERE ($1::bigint IS NULL OR internal_id < $1::bigint)
+  ORDER BY internal_id DESC
+  LIMIT $2`, id, first+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conn := &TweetConnection{Edges: []*TweetEdge{}}
+	var last *Cursor
+	count := 0
+	for rows.Next() {
+		var internalID int64
+		var uris []string
+		tweet := new(Tweet)
+		if err := rows.Scan(
+			&internalID,
+			&tweet.ID,
+			&tweet.Text,
+			pq.Array(&tweet.Hashtags),
+			pq.Array(&tweet.Symbols),
+			pq.Array(&tweet.UserMentions),
+			pq.Array(&uris),
+			&tweet.ScreenName,
+			&tweet.FavoriteCount,
+			&tweet.RetweetCount,
+			&tweet.Posted,
+		); err != nil {
+			return nil, err
+		}
+
+		count++
+		if count > first {
+			break
+		}
+
+		for _, v := range uris {
+			tweet.Urls = append(tweet.Urls, NewURI(v))
+		}
+
+		last = &Cursor{ID: fmt.Sprintf("%d", internalID)}
+		conn.Edges = append(conn.Edges, &TweetEdge{Cursor: last.String(), Node: tweet})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	conn.PageInfo = newPageInfo(count, first, last)
+
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tweets").Scan(&conn.TotalCount); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
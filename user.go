@@ -4,15 +4,22 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
 )
 
+// maxUserNameLength is the longest name a user can have.
+const maxUserNameLength = 100
+
 // User is a database object based off of what we get back from Google OAuth.
 type User struct {
 	ID       string
 	Name     string
 	Role     string
-	APIKey   string
+	Disabled *time.Time
 	Created  time.Time
 	Modified time.Time
 }
@@ -41,35 +48,295 @@ func (u *User) Save(ctx context.Context) error {
 }
 
 // GetUser returns a user from the database. If the User does not exist, we
-// create it.
+// create it, so it should only be used when someone signs in. Use FindUser
+// everywhere else. Signing in only touches an existing user's modified time,
+// so it never undoes a role or name change made at the same time.
 func GetUser(ctx context.Context, id string) (*User, error) {
-	var user User
-	row := db.QueryRowContext(ctx, "SELECT id, role, apikey, name, created_at, modified_at FROM users WHERE id = $1", id)
-	err := row.Scan(&user.ID, &user.Role, &user.APIKey, &user.Name, &user.Created, &user.Modified)
+	if _, err := db.ExecContext(ctx, `
+INSERT INTO users (id, role, name, created_at, modified_at)
+VALUES ($1, $2, $3, NOW(), NOW())
+ON CONFLICT (id) DO UPDATE
+SET modified_at = NOW()
+`, id, RoleNormal, "anonymous"); err != nil {
+		return nil, fmt.Errorf("error with get: %w", err)
+	}
+
+	return FindUser(ctx, id)
+}
+
+// FindUser returns a user from the database, or sql.ErrNoRows if there is no
+// user with that ID.
+func FindUser(ctx context.Context, id string) (*User, error) {
+	users, err := userQuery(ctx, "SELECT id, role, name, disabled_at, created_at, modified_at FROM users WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return users[0], nil
+}
+
+// GetUsers returns users, oldest first. If role is set, only users with that
+// role are returned.
+func GetUsers(ctx context.Context, role *Role, limit, offset int) ([]*User, error) {
+	return userQuery(ctx, `
+SELECT id, role, name, disabled_at, created_at, modified_at
+FROM users
+WHERE ($1::text IS NULL OR role = $1)
+ORDER BY created_at ASC, id ASC
+LIMIT $2 OFFSET $3
+`, role, limit, offset)
+}
+
+func userQuery(ctx context.Context, query string, args ...interface{}) ([]*User, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*User, 0)
+	for rows.Next() {
+		u := new(User)
+		var disabled sql.NullTime
+		if err := rows.Scan(&u.ID, &u.Role, &u.Name, &disabled, &u.Created, &u.Modified); err != nil {
+			return nil, err
+		}
+
+		if disabled.Valid {
+			u.Disabled = &disabled.Time
+		}
+
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
 
+// CheckEnabled returns an error if the user has been disabled.
+func (u *User) CheckEnabled() error {
+	if u.Disabled != nil {
+		return fmt.Errorf("user %q is disabled", u.ID)
+	}
+
+	return nil
+}
+
+// adminChangeableUser returns the user with the ID, if admin is allowed to
+// change their role, disable or delete them. Admins can't do these to
+// themselves, so there is always an admin left to undo them.
+func adminChangeableUser(ctx context.Context, admin *User, id string) (*User, error) {
+	if admin.ID == id {
+		return nil, fmt.Errorf("you cannot change your own account this way")
+	}
+
+	u, err := FindUser(ctx, id)
 	switch {
 	case err == sql.ErrNoRows:
-		user.ID = id
-		user.Role = "normal"
-		user.Name = "anonymous"
-		user.Created = time.Now()
-		user.Modified = time.Now()
-		return &user, (&user).Save(ctx)
+		return nil, fmt.Errorf("no user %q", id)
 	case err != nil:
-		return nil, fmt.Errorf("error with get: %w", err)
-	default:
-		return &user, (&user).Save(ctx)
+		return nil, err
 	}
+
+	return u, nil
 }
 
-// GetUserByAPIKey returns a user from the database.
-func GetUserByAPIKey(ctx context.Context, apikey string) (*User, error) {
-	var user User
-	row := db.QueryRowContext(ctx, "SELECT id, role, apikey, name, created_at, modified_at FROM users WHERE apikey = $1", apikey)
-	err := row.Scan(&user.ID, &user.Role, &user.APIKey, &user.Name, &user.Created, &user.Modified)
+// SetUserRole changes the role of a user.
+func SetUserRole(ctx context.Context, admin *User, id string, role Role) (*User, error) {
+	if !role.IsValid() {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+
+	u, err := adminChangeableUser(ctx, admin, id)
 	if err != nil {
-		return nil, fmt.Errorf("error with get: %w", err)
+		return nil, err
+	}
+
+	u.Role = role.String()
+	if err := u.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return FindUser(ctx, id)
+}
+
+// RenameUser changes the name of a user.
+func RenameUser(ctx context.Context, id, name string) (*User, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("name cannot be empty")
+	}
+
+	if len(name) > maxUserNameLength {
+		return nil, fmt.Errorf("name cannot be longer than %d characters", maxUserNameLength)
+	}
+
+	u, err := FindUser(ctx, id)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, fmt.Errorf("no user %q", id)
+	case err != nil:
+		return nil, err
+	}
+
+	u.Name = name
+	if err := u.Save(ctx); err != nil {
+		return nil, err
 	}
 
-	return &user, (&user).Save(ctx)
+	return FindUser(ctx, id)
+}
+
+// SetUserDisabled disables or re-enables a user. Disabled users can't sign in
+// or use their API keys, but keep their content.
+func SetUserDisabled(ctx context.Context, admin *User, id string, disabled bool) (*User, error) {
+	if _, err := adminChangeableUser(ctx, admin, id); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+UPDATE users
+SET (disabled_at, modified_at) = (CASE WHEN $2 THEN COALESCE(disabled_at, NOW()) END, NOW())
+WHERE id = $1
+`, id, disabled); err != nil {
+		return nil, err
+	}
+
+	return FindUser(ctx, id)
+}
+
+// DeleteUser removes a user and everything they made: their comments, logs,
+// photos and API keys. Replies by other users to deleted comments move up to
+// the deleted comment's parent. Post revisions they made are kept, without a
+// user.
+func DeleteUser(ctx context.Context, admin *User, id string) (*User, error) {
+	u, err := adminChangeableUser(ctx, admin, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := deleteUserComments(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM logs WHERE user_id = $1", id); err != nil {
+		return nil, fmt.Errorf("delete logs: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, "DELETE FROM photos WHERE user_id = $1 RETURNING id, year, content_type", id)
+	if err != nil {
+		return nil, fmt.Errorf("delete photos: %w", err)
+	}
+	defer rows.Close()
+
+	var photos []*Photo
+	for rows.Next() {
+		p := &Photo{User: *u}
+		if err := rows.Scan(&p.ID, &p.Year, &p.ContentType); err != nil {
+			return nil, err
+		}
+		photos = append(photos, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE post_revisions SET user_id = NULL WHERE user_id = $1", id); err != nil {
+		return nil, fmt.Errorf("clear revisions: %w", err)
+	}
+
+	// API keys are removed by their foreign key.
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id); err != nil {
+		return nil, fmt.Errorf("delete user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Files are removed after the rows are gone, so a failure here leaves
+	// unreachable files rather than broken photos.
+	for _, p := range photos {
+		if err := p.DeleteFile(ctx); err != nil {
+			log.Warnw("could not delete photo file", "photo", p.ID, zap.Error(err))
+		}
+	}
+
+	return u, nil
+}
+
+// deleteUserComments removes a user's comments and the edit history of
+// them, keeping other users' replies in their threads.
+func deleteUserComments(ctx context.Context, tx *sql.Tx, id string) error {
+	var posts []int64
+	if err := tx.QueryRowContext(ctx, "SELECT ARRAY(SELECT DISTINCT post_id FROM comments WHERE user_id = $1 AND post_id IS NOT NULL)", id).Scan(pq.Array(&posts)); err != nil {
+		return fmt.Errorf("find commented posts: %w", err)
+	}
+
+	// Move replies up past the user's comments. A user can reply to
+	// themselves, so this repeats until no reply has a parent by them.
+	for i := 0; i <= MaxCommentDepth; i++ {
+		res, err := tx.ExecContext(ctx, `
+UPDATE comments c
+SET parent_id = p.parent_id
+FROM comments p
+WHERE c.parent_id = p.id AND p.user_id = $1 AND c.user_id <> $1
+`, id)
+		if err != nil {
+			return fmt.Errorf("move replies: %w", err)
+		}
+
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			break
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM comment_edits WHERE user_id = $1", id); err != nil {
+		return fmt.Errorf("delete comment edits: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM comments WHERE user_id = $1", id); err != nil {
+		return fmt.Errorf("delete comments: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+WITH RECURSIVE thread AS (
+  SELECT id, 0 AS depth FROM comments WHERE parent_id IS NULL AND post_id = ANY($1)
+  UNION ALL
+  SELECT c.id, thread.depth + 1 FROM comments c JOIN thread ON c.parent_id = thread.id
+)
+UPDATE comments
+SET depth = thread.depth
+FROM thread
+WHERE comments.id = thread.id AND comments.depth <> thread.depth
+`, pq.Array(posts)); err != nil {
+		return fmt.Errorf("update comment depths: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateProfile changes the signed in user's own name.
+func UpdateProfile(ctx context.Context, u *User, name string) (*User, error) {
+	return RenameUser(ctx, u.ID, name)
+}
+
+// APIKey is always empty. API keys are hashed, so they are only shown when
+// they are created.
+func (u *User) APIKey() string {
+	return ""
 }
@@ -0,0 +1,83 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.41
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SetUserRole is the resolver for the setUserRole field.
+func (r *mutationResolver) SetUserRole(ctx context.Context, id string, role Role) (*User, error) {
+	u := GetUserFromContext(ctx)
+	if u == nil {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	return SetUserRole(ctx, u, id, role)
+}
+
+// RenameUser is the resolver for the renameUser field.
+func (r *mutationResolver) RenameUser(ctx context.Context, id string, name string) (*User, error) {
+	return RenameUser(ctx, id, name)
+}
+
+// DisableUser is the resolver for the disableUser field.
+func (r *mutationResolver) DisableUser(ctx context.Context, id string) (*User, error) {
+	u := GetUserFromContext(ctx)
+	if u == nil {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	return SetUserDisabled(ctx, u, id, true)
+}
+
+// EnableUser is the resolver for the enableUser field.
+func (r *mutationResolver) EnableUser(ctx context.Context, id string) (*User, error) {
+	u := GetUserFromContext(ctx)
+	if u == nil {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	return SetUserDisabled(ctx, u, id, false)
+}
+
+// DeleteUser is the resolver for the deleteUser field.
+func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (*User, error) {
+	u := GetUserFromContext(ctx)
+	if u == nil {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	return DeleteUser(ctx, u, id)
+}
+
+// UpdateMyProfile is the resolver for the updateMyProfile field.
+func (r *mutationResolver) UpdateMyProfile(ctx context.Context, input EditProfile) (*User, error) {
+	u := GetUserFromContext(ctx)
+	if u == nil {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	return UpdateProfile(ctx, u, input.Name)
+}
+
+// Users is the resolver for the users field.
+func (r *queryResolver) Users(ctx context.Context, role *Role, input *Limit) ([]*User, error) {
+	limit, offset := ParseLimit(input, 25, 0)
+
+	return GetUsers(ctx, role, limit, offset)
+}
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id string) (*User, error) {
+	u, err := FindUser(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return u, err
+}
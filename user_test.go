@@ -0,0 +1,85 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCheckEnabled(t *testing.T) {
+	now := time.Now()
+	if err := (&User{ID: "a"}).CheckEnabled(); err != nil {
+		t.Errorf("CheckEnabled() = %v for enabled user", err)
+	}
+
+	if err := (&User{ID: "a", Disabled: &now}).CheckEnabled(); err == nil {
+		t.Error("CheckEnabled() succeeded for disabled user")
+	}
+}
+
+func TestUserChangesWithoutDB(t *testing.T) {
+	ctx := context.Background()
+	admin := &User{ID: "admin", Role: "admin"}
+
+	tests := map[string]func() error{
+		"demote self": func() error {
+			_, err := SetUserRole(ctx, admin, admin.ID, RoleNormal)
+			return err
+		},
+		"invalid role": func() error {
+			_, err := SetUserRole(ctx, admin, "other", Role("root"))
+			return err
+		},
+		"disable self": func() error {
+			_, err := SetUserDisabled(ctx, admin, admin.ID, true)
+			return err
+		},
+		"delete self": func() error {
+			_, err := DeleteUser(ctx, admin, admin.ID)
+			return err
+		},
+		"empty name": func() error {
+			_, err := RenameUser(ctx, "other", "  ")
+			return err
+		},
+		"long name": func() error {
+			_, err := UpdateProfile(ctx, admin, strings.Repeat("a", maxUserNameLength+1))
+			return err
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if err := tc(); err == nil {
+				t.Error("succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestGetUserKeepsRoleAndName(t *testing.T) {
+	mock := mockDB(t)
+	now := time.Now()
+
+	mock.ExpectExec(`INSERT INTO users .* ON CONFLICT \(id\) DO UPDATE\s+SET modified_at = NOW\(\)\s*$`).
+		WithArgs("a", RoleNormal, "anonymous").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id, role, name, disabled_at, created_at, modified_at FROM users").
+		WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role", "name", "disabled_at", "created_at", "modified_at"}).
+			AddRow("a", "admin", "Nat", nil, now, now))
+
+	u, err := GetUser(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("GetUser() = %v", err)
+	}
+
+	if u.Role != string(RoleAdmin) || u.Name != "Nat" {
+		t.Errorf("GetUser() = %+v, want the stored role and name", u)
+	}
+}
@@ -0,0 +1,314 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Webhook is a URL that is sent a signed JSON payload every time one of the
+// events it subscribes to happens.
+type Webhook struct {
+	ID       string         `json:"id"`
+	URL      URI            `json:"url"`
+	Secret   string         `json:"-"`
+	Events   []WebhookEvent `json:"events"`
+	Active   bool           `json:"active"`
+	Created  time.Time      `json:"created"`
+	Modified time.Time      `json:"modified"`
+}
+
+// Name returns the name of the event used in payloads, such as
+// "post.published".
+func (e WebhookEvent) Name() string {
+	return strings.Replace(e.String(), "_", ".", 1)
+}
+
+// Save validates and upserts a webhook.
+func (w *Webhook) Save(ctx context.Context) error {
+	u, err := url.Parse(w.URL.String())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url %q must be an absolute http or https url", w.URL)
+	}
+
+	if w.Secret == "" {
+		return fmt.Errorf("webhook secret cannot be empty")
+	}
+
+	if len(w.Events) == 0 {
+		return fmt.Errorf("webhook must subscribe to at least one event")
+	}
+
+	events := make([]string, len(w.Events))
+	for i, e := range w.Events {
+		if !e.IsValid() {
+			return fmt.Errorf("invalid webhook event %q", e)
+		}
+		events[i] = e.String()
+	}
+
+	if w.Created.IsZero() {
+		w.Created = time.Now()
+	}
+	w.Modified = time.Now()
+
+	if w.ID == "" {
+		row := db.QueryRowContext(ctx, `
+INSERT INTO webhooks(url, secret, events, active, created_at, modified_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id
+`, w.URL, w.Secret, pq.Array(events), w.Active, w.Created, w.Modified)
+
+		return row.Scan(&w.ID)
+	}
+
+	res, err := db.ExecContext(ctx, `
+UPDATE webhooks
+SET (url, secret, events, active, modified_at) = ($2, $3, $4, $5, $6)
+WHERE id = $1
+`, w.ID, w.URL, w.Secret, pq.Array(events), w.Active, w.Modified)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no webhook %q", w.ID)
+	}
+
+	return nil
+}
+
+// Deliveries returns the most recent deliveries to this webhook.
+func (w *Webhook) Deliveries(ctx context.Context, input *Limit) ([]*WebhookDelivery, error) {
+	limit, offset := ParseLimit(input, 10, 0)
+
+	return GetWebhookDeliveries(ctx, &w.ID, nil, limit, offset)
+}
+
+// GetWebhook gets a webhook by ID.
+func GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	hooks, err := webhookQuery(ctx, "SELECT id, url, secret, events, active, created_at, modified_at FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hooks) == 0 {
+		return nil, fmt.Errorf("no webhook %q", id)
+	}
+
+	return hooks[0], nil
+}
+
+// GetWebhooks returns all webhooks.
+func GetWebhooks(ctx context.Context) ([]*Webhook, error) {
+	return webhookQuery(ctx, "SELECT id, url, secret, events, active, created_at, modified_at FROM webhooks ORDER BY id")
+}
+
+// DeleteWebhook removes a webhook and its deliveries.
+func DeleteWebhook(ctx context.Context, id string) (*Webhook, error) {
+	w, err := GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func webhookQuery(ctx context.Context, query string, args ...interface{}) ([]*Webhook, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]*Webhook, 0)
+	for rows.Next() {
+		w := new(Webhook)
+		var events []string
+		if err := rows.Scan(&w.ID, &w.URL, &w.Secret, pq.Array(&events), &w.Active, &w.Created, &w.Modified); err != nil {
+			return nil, err
+		}
+
+		for _, e := range events {
+			w.Events = append(w.Events, WebhookEvent(e))
+		}
+
+		hooks = append(hooks, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// enqueueEvent adds a delivery of an event to the outbox for every active
+// webhook subscribed to it. If e is a transaction, the deliveries are only
+// made if it commits.
+func enqueueEvent(ctx context.Context, e execer, event WebhookEvent, data interface{}) error {
+	blob, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", event.Name(), err)
+	}
+
+	if _, err := e.ExecContext(ctx, `
+INSERT INTO webhook_deliveries(webhook_id, event, data, status, attempts, next_attempt_at, created_at)
+SELECT id, $1, $2, 'pending', 0, NOW(), NOW()
+FROM webhooks
+WHERE active = true
+  AND $1 = ANY(events)
+`, event.String(), string(blob)); err != nil {
+		return fmt.Errorf("enqueue %s event: %w", event.Name(), err)
+	}
+
+	return nil
+}
+
+// publishedPostData is the data sent with post.published events.
+func publishedPostData(p *Post) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        p.ID,
+		"title":     p.Title,
+		"slug":      p.Slug,
+		"uri":       p.URI().String(),
+		"published": p.Datetime,
+	}
+}
+
+// WebhookDelivery is a single event waiting to be, or already, sent to a
+// webhook.
+type WebhookDelivery struct {
+	ID           string                `json:"id"`
+	WebhookID    string                `json:"webhook_id"`
+	Event        WebhookEvent          `json:"event"`
+	Data         string                `json:"data"`
+	Status       WebhookDeliveryStatus `json:"status"`
+	AttemptCount int                   `json:"attempt_count"`
+	NextAttempt  *time.Time            `json:"next_attempt"`
+	Created      time.Time             `json:"created"`
+	Delivered    *time.Time            `json:"delivered"`
+}
+
+// Webhook returns the webhook this delivery is for.
+func (d *WebhookDelivery) Webhook(ctx context.Context) (*Webhook, error) {
+	return GetWebhook(ctx, d.WebhookID)
+}
+
+// WebhookAttempt is a single try at sending a delivery.
+type WebhookAttempt struct {
+	ID         string    `json:"id"`
+	Attempted  time.Time `json:"attempted"`
+	StatusCode *int      `json:"status_code"`
+	Error      *string   `json:"error"`
+	Duration   Duration  `json:"duration"`
+}
+
+// Attempts returns every attempt at sending this delivery, newest first.
+func (d *WebhookDelivery) Attempts(ctx context.Context) ([]*WebhookAttempt, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT id, attempted_at, status_code, error, duration
+FROM webhook_attempts
+WHERE delivery_id = $1
+ORDER BY id DESC
+`, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]*WebhookAttempt, 0)
+	for rows.Next() {
+		a := new(WebhookAttempt)
+		var code sql.NullInt64
+		var msg sql.NullString
+		var secs float64
+		if err := rows.Scan(&a.ID, &a.Attempted, &code, &msg, &secs); err != nil {
+			return nil, err
+		}
+		a.Duration = NewDuration(secs)
+
+		if code.Valid {
+			c := int(code.Int64)
+			a.StatusCode = &c
+		}
+
+		if msg.Valid {
+			a.Error = &msg.String
+		}
+
+		attempts = append(attempts, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// GetWebhookDeliveries returns deliveries, newest first, optionally only for
+// one webhook or with one status.
+func GetWebhookDeliveries(ctx context.Context, webhookID *string, status *WebhookDeliveryStatus, limit, offset int) ([]*WebhookDelivery, error) {
+	return webhookDeliveryQuery(ctx, `
+SELECT id, webhook_id, event, data, status, attempts, next_attempt_at, created_at, delivered_at
+FROM webhook_deliveries
+WHERE ($1::bigint IS NULL OR webhook_id = $1::bigint)
+  AND ($2::text IS NULL OR status = $2::text)
+ORDER BY id DESC
+LIMIT $3 OFFSET $4
+`, webhookID, status, limit, offset)
+}
+
+// RetryWebhookDelivery queues a delivery to be sent again as soon as
+// possible, even if it has already failed or been delivered.
+func RetryWebhookDelivery(ctx context.Context, id string) (*WebhookDelivery, error) {
+	ds, err := webhookDeliveryQuery(ctx, `
+UPDATE webhook_deliveries
+SET (status, next_attempt_at, delivered_at) = ('pending', NOW(), NULL)
+WHERE id = $1
+RETURNING id, webhook_id, event, data, status, attempts, next_attempt_at, created_at, delivered_at
+`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ds) == 0 {
+		return nil, fmt.Errorf("no webhook delivery %q", id)
+	}
+
+	return ds[0], nil
+}
+
+func webhookDeliveryQuery(ctx context.Context, query string, args ...interface{}) ([]*WebhookDelivery, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ds := make([]*WebhookDelivery, 0)
+	for rows.Next() {
+		d := new(WebhookDelivery)
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Data, &d.Status, &d.AttemptCount, &d.NextAttempt, &d.Created, &d.Delivered); err != nil {
+			return nil, err
+		}
+		ds = append(ds, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
@@ -0,0 +1,81 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.41
+
+import (
+	"context"
+)
+
+// CreateWebhook is the resolver for the createWebhook field.
+func (r *mutationResolver) CreateWebhook(ctx context.Context, input NewWebhook) (*Webhook, error) {
+	w := &Webhook{
+		URL:    input.URL,
+		Secret: input.Secret,
+		Events: input.Events,
+		Active: true,
+	}
+
+	if input.Active != nil {
+		w.Active = *input.Active
+	}
+
+	if err := w.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return GetWebhook(ctx, w.ID)
+}
+
+// EditWebhook is the resolver for the editWebhook field.
+func (r *mutationResolver) EditWebhook(ctx context.Context, id string, input EditWebhook) (*Webhook, error) {
+	w, err := GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.URL != nil {
+		w.URL = *input.URL
+	}
+
+	if input.Secret != nil {
+		w.Secret = *input.Secret
+	}
+
+	if input.Events != nil {
+		w.Events = input.Events
+	}
+
+	if input.Active != nil {
+		w.Active = *input.Active
+	}
+
+	if err := w.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return GetWebhook(ctx, w.ID)
+}
+
+// DeleteWebhook is the resolver for the deleteWebhook field.
+func (r *mutationResolver) DeleteWebhook(ctx context.Context, id string) (*Webhook, error) {
+	return DeleteWebhook(ctx, id)
+}
+
+// RetryWebhookDelivery is the resolver for the retryWebhookDelivery field.
+func (r *mutationResolver) RetryWebhookDelivery(ctx context.Context, id string) (*WebhookDelivery, error) {
+	return RetryWebhookDelivery(ctx, id)
+}
+
+// Webhooks is the resolver for the webhooks field.
+func (r *queryResolver) Webhooks(ctx context.Context) ([]*Webhook, error) {
+	return GetWebhooks(ctx)
+}
+
+// WebhookDeliveries is the resolver for the webhookDeliveries field.
+func (r *queryResolver) WebhookDeliveries(ctx context.Context, webhookID *string, status *WebhookDeliveryStatus, input *Limit) ([]*WebhookDelivery, error) {
+	limit, offset := ParseLimit(input, 25, 0)
+
+	return GetWebhookDeliveries(ctx, webhookID, status, limit, offset)
+}
@@ -0,0 +1,222 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// MaxWebhookAttempts is how many times a delivery is tried before it is
+	// marked as failed.
+	MaxWebhookAttempts = 10
+
+	// webhookBackoff is how long to wait after the first failed attempt. The
+	// wait doubles after every attempt, up to maxWebhookBackoff.
+	webhookBackoff    = 30 * time.Second
+	maxWebhookBackoff = 12 * time.Hour
+
+	// webhookLease is how long a claimed delivery is hidden from other
+	// workers. If a worker dies mid-delivery, it is retried after this.
+	webhookLease = 5 * time.Minute
+
+	// WebhookSignatureHeader holds the hex encoded HMAC-SHA256 of the request
+	// body, keyed with the webhook's secret, prefixed with "sha256=".
+	WebhookSignatureHeader = "X-Webhook-Signature"
+
+	// WebhookEventHeader holds the name of the event, such as "post.published".
+	WebhookEventHeader = "X-Webhook-Event"
+
+	// WebhookDeliveryHeader holds the ID of the delivery. It is the same for
+	// every attempt, so receivers can ignore duplicates.
+	WebhookDeliveryHeader = "X-Webhook-Delivery"
+)
+
+// WebhookPayload is the JSON body sent to webhooks.
+type WebhookPayload struct {
+	ID      string          `json:"id"`
+	Event   string          `json:"event"`
+	Created time.Time       `json:"created"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// SignWebhook returns the signature of a webhook body, as sent in the
+// WebhookSignatureHeader.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoffAfter returns how long to wait before trying a delivery
+// again, after it has failed attempts times.
+func webhookBackoffAfter(attempts int) time.Duration {
+	d := webhookBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxWebhookBackoff {
+			return maxWebhookBackoff
+		}
+	}
+
+	return d
+}
+
+// SendWebhook posts a payload to a webhook URL and signs it with the secret.
+// Responses other than 2xx are errors. The status code is returned whenever
+// there was a response.
+func SendWebhook(ctx context.Context, client *http.Client, url, secret string, payload *WebhookPayload) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("%s-webhooks", AppName))
+	req.Header.Set(WebhookEventHeader, payload.Event)
+	req.Header.Set(WebhookDeliveryHeader, payload.ID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Read some of the body so the connection can be reused.
+	if _, err := io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)); err != nil {
+		return resp.StatusCode, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+type claimedDelivery struct {
+	payload WebhookPayload
+	url     URI
+	secret  string
+}
+
+// DeliverWebhooks sends up to limit deliveries that are due, and records how
+// each attempt went. Failed deliveries are retried with exponential backoff
+// until MaxWebhookAttempts is reached. It returns the number of deliveries
+// that succeeded.
+func DeliverWebhooks(ctx context.Context, client *http.Client, limit int) (int, error) {
+	rows, err := db.QueryContext(ctx, `
+UPDATE webhook_deliveries d
+SET next_attempt_at = NOW() + $2::interval
+FROM webhooks w
+WHERE w.id = d.webhook_id
+  AND d.id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending'
+      AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING d.id, d.event, d.data, d.created_at, w.url, w.secret
+`, limit, fmt.Sprintf("%d seconds", int(webhookLease.Seconds())))
+	if err != nil {
+		return 0, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+
+	var claimed []*claimedDelivery
+	for rows.Next() {
+		c := new(claimedDelivery)
+		var event WebhookEvent
+		var data string
+		if err := rows.Scan(&c.payload.ID, &event, &data, &c.payload.Created, &c.url, &c.secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		c.payload.Event = event.Name()
+		c.payload.Data = json.RawMessage(data)
+		claimed = append(claimed, c)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, c := range claimed {
+		start := time.Now()
+		code, sendErr := SendWebhook(ctx, client, c.url.String(), c.secret, &c.payload)
+		if err := recordWebhookAttempt(ctx, c.payload.ID, code, sendErr, time.Since(start)); err != nil {
+			return delivered, err
+		}
+
+		if sendErr == nil {
+			delivered++
+		} else {
+			log.Warnw("webhook delivery failed", "delivery", c.payload.ID, "url", c.url, "status", code, "error", sendErr)
+		}
+	}
+
+	return delivered, nil
+}
+
+// recordWebhookAttempt saves an attempt and schedules the next one if it
+// failed.
+func recordWebhookAttempt(ctx context.Context, id string, code int, sendErr error, took time.Duration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var statusCode *int
+	if code != 0 {
+		statusCode = &code
+	}
+
+	var msg *string
+	if sendErr != nil {
+		s := sendErr.Error()
+		msg = &s
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO webhook_attempts(delivery_id, attempted_at, status_code, error, duration)
+VALUES ($1, NOW(), $2, $3, $4)
+`, id, statusCode, msg, took.Seconds()); err != nil {
+		return fmt.Errorf("record webhook attempt: %w", err)
+	}
+
+	var attempts int
+	if err := tx.QueryRowContext(ctx, "UPDATE webhook_deliveries SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts", id).Scan(&attempts); err != nil {
+		return fmt.Errorf("count webhook attempt: %w", err)
+	}
+
+	switch {
+	case sendErr == nil:
+		_, err = tx.ExecContext(ctx, "UPDATE webhook_deliveries SET (status, next_attempt_at, delivered_at) = ('delivered', NULL, NOW()) WHERE id = $1", id)
+	case attempts >= MaxWebhookAttempts:
+		_, err = tx.ExecContext(ctx, "UPDATE webhook_deliveries SET (status, next_attempt_at) = ('failed', NULL) WHERE id = $1", id)
+	default:
+		_, err = tx.ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = $2 WHERE id = $1", id, time.Now().Add(webhookBackoffAfter(attempts)))
+	}
+	if err != nil {
+		return fmt.Errorf("update webhook delivery: %w", err)
+	}
+
+	return tx.Commit()
+}
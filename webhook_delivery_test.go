@@ -0,0 +1,114 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendWebhook(t *testing.T) {
+	const secret = "shh"
+	payload := &WebhookPayload{
+		ID:      "42",
+		Event:   WebhookEventPostPublished.Name(),
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:    json.RawMessage(`{"id":"1"}`),
+	}
+
+	var got WebhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if sig := r.Header.Get(WebhookSignatureHeader); sig != SignWebhook(secret, body) {
+			t.Errorf("signature = %q, want %q", sig, SignWebhook(secret, body))
+		}
+
+		if ev := r.Header.Get(WebhookEventHeader); ev != "post.published" {
+			t.Errorf("event header = %q, want post.published", ev)
+		}
+
+		if id := r.Header.Get(WebhookDeliveryHeader); id != "42" {
+			t.Errorf("delivery header = %q, want 42", id)
+		}
+
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("unmarshal body: %v", err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	code, err := SendWebhook(context.Background(), srv.Client(), srv.URL, secret, payload)
+	if err != nil {
+		t.Fatalf("SendWebhook: %v", err)
+	}
+
+	if code != http.StatusNoContent {
+		t.Errorf("code = %d, want %d", code, http.StatusNoContent)
+	}
+
+	if got.ID != payload.ID || got.Event != payload.Event || string(got.Data) != string(payload.Data) || !got.Created.Equal(payload.Created) {
+		t.Errorf("received %+v, want %+v", got, payload)
+	}
+}
+
+func TestSendWebhookFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	code, err := SendWebhook(context.Background(), srv.Client(), srv.URL, "shh", &WebhookPayload{ID: "1", Data: json.RawMessage(`{}`)})
+	if err == nil {
+		t.Error("SendWebhook succeeded, want error")
+	}
+
+	if code != http.StatusInternalServerError {
+		t.Errorf("code = %d, want %d", code, http.StatusInternalServerError)
+	}
+}
+
+func TestWebhookBackoffAfter(t *testing.T) {
+	tests := map[string]struct {
+		attempts int
+		want     time.Duration
+	}{
+		"first":  {attempts: 1, want: 30 * time.Second},
+		"second": {attempts: 2, want: time.Minute},
+		"fifth":  {attempts: 5, want: 8 * time.Minute},
+		"capped": {attempts: 20, want: 12 * time.Hour},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := webhookBackoffAfter(tc.attempts); got != tc.want {
+				t.Errorf("webhookBackoffAfter(%d) = %v, want %v", tc.attempts, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWebhookEventName(t *testing.T) {
+	for e, want := range map[WebhookEvent]string{
+		WebhookEventPostPublished: "post.published",
+		WebhookEventCommentAdded:  "comment.added",
+		WebhookEventLinkUpserted:  "link.upserted",
+		WebhookEventStatUpserted:  "stat.upserted",
+	} {
+		if got := e.Name(); got != want {
+			t.Errorf("%s.Name() = %q, want %q", e, got, want)
+		}
+	}
+}
@@ -0,0 +1,437 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxWebmentionBody is the most of a page read when verifying a mention or
+// discovering an endpoint.
+const maxWebmentionBody = 1 << 20
+
+// Fetcher makes HTTP requests for webmentions. *http.Client satisfies it, and
+// tests can use a stub.
+type Fetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Webmention is a page on another site that links to one of our posts.
+type Webmention struct {
+	ID       string    `json:"id"`
+	PostID   string    `json:"post_id"`
+	Source   URI       `json:"source"`
+	Target   URI       `json:"target"`
+	Title    string    `json:"title"`
+	Created  time.Time `json:"created"`
+	Modified time.Time `json:"modified"`
+}
+
+// Post returns the post that was mentioned.
+func (m *Webmention) Post(ctx context.Context) (*Post, error) {
+	return GetPostString(ctx, m.PostID)
+}
+
+// Mentions returns the webmentions of a post, oldest first.
+func (p *Post) Mentions(ctx context.Context, input *Limit) ([]*Webmention, error) {
+	limit, offset := ParseLimit(input, 100, 0)
+
+	rows, err := db.QueryContext(ctx, `
+SELECT id, post_id, source, target, title, created_at, modified_at
+FROM webmentions
+WHERE post_id = $1
+ORDER BY created_at ASC
+LIMIT $2 OFFSET $3
+`, p.ID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := make([]*Webmention, 0)
+	for rows.Next() {
+		m := new(Webmention)
+		if err := rows.Scan(&m.ID, &m.PostID, &m.Source, &m.Target, &m.Title, &m.Created, &m.Modified); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return mentions, nil
+}
+
+// WebmentionError is a problem with a received webmention that is the
+// sender's fault.
+type WebmentionError struct {
+	msg string
+}
+
+func (e *WebmentionError) Error() string {
+	return e.msg
+}
+
+func webmentionErrorf(format string, a ...interface{}) error {
+	return &WebmentionError{msg: fmt.Sprintf(format, a...)}
+}
+
+// parseWebURL parses an absolute http or https URL.
+func parseWebURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http or https url", raw)
+	}
+
+	return u, nil
+}
+
+// postForTarget finds the published post a webmention target points at.
+func postForTarget(ctx context.Context, target *url.URL) (*Post, error) {
+	base, err := url.Parse(FeedLink)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(target.Host, base.Host) || !strings.HasPrefix(target.Path, "/post/") {
+		return nil, webmentionErrorf("target %q is not a post on %s", target, base.Host)
+	}
+
+	id := strings.Trim(strings.TrimPrefix(target.Path, "/post/"), "/")
+	if id == "" {
+		return nil, webmentionErrorf("target %q is not a post on %s", target, base.Host)
+	}
+
+	p, err := GetPostString(ctx, id)
+	if err != nil || p == nil || !p.IsPublished() {
+		return nil, webmentionErrorf("no post at %q", target)
+	}
+
+	return p, nil
+}
+
+// CheckWebmention makes sure source and target are http urls and that target
+// is one of our published posts, without fetching anything. Errors that are
+// the sender's fault are a *WebmentionError.
+func CheckWebmention(ctx context.Context, source, target string) error {
+	_, _, _, err := checkWebmention(ctx, source, target)
+	return err
+}
+
+func checkWebmention(ctx context.Context, source, target string) (*url.URL, *url.URL, *Post, error) {
+	src, err := parseWebURL(source)
+	if err != nil {
+		return nil, nil, nil, webmentionErrorf("invalid source: %s", err)
+	}
+
+	tgt, err := parseWebURL(target)
+	if err != nil {
+		return nil, nil, nil, webmentionErrorf("invalid target: %s", err)
+	}
+
+	if src.String() == tgt.String() {
+		return nil, nil, nil, webmentionErrorf("source and target must be different")
+	}
+
+	p, err := postForTarget(ctx, tgt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return src, tgt, p, nil
+}
+
+// ReceiveWebmention verifies that source links to target, which must be one
+// of our posts, and stores the mention. If source is gone or no longer links
+// to target, any mention already stored is removed. Errors that are the
+// sender's fault are a *WebmentionError.
+func ReceiveWebmention(ctx context.Context, f Fetcher, source, target string) (*Webmention, error) {
+	src, tgt, p, err := checkWebmention(ctx, source, target)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.Do(req)
+	if err != nil {
+		return nil, webmentionErrorf("could not fetch source: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var title string
+	found := false
+	switch {
+	case resp.StatusCode == http.StatusGone:
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		title, found, err = linksTo(io.LimitReader(resp.Body, maxWebmentionBody), src, tgt.String())
+		if err != nil {
+			return nil, webmentionErrorf("could not parse source: %s", err)
+		}
+	default:
+		return nil, webmentionErrorf("fetching source returned %s", resp.Status)
+	}
+
+	if !found {
+		if _, err := db.ExecContext(ctx, "DELETE FROM webmentions WHERE source = $1 AND target = $2", src.String(), tgt.String()); err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusGone {
+			return nil, nil
+		}
+
+		return nil, webmentionErrorf("source does not link to target")
+	}
+
+	if title == "" {
+		title = src.String()
+	}
+
+	m := &Webmention{PostID: p.ID, Source: *NewURI(src.String()), Target: *NewURI(tgt.String()), Title: title}
+	row := db.QueryRowContext(ctx, `
+INSERT INTO webmentions(post_id, source, target, title, created_at, modified_at)
+VALUES ($1, $2, $3, $4, NOW(), NOW())
+ON CONFLICT (source, target) DO UPDATE
+SET (post_id, title, modified_at) = ($1, $4, NOW())
+RETURNING id, created_at, modified_at
+`, m.PostID, src.String(), tgt.String(), m.Title)
+	if err := row.Scan(&m.ID, &m.Created, &m.Modified); err != nil {
+		return nil, fmt.Errorf("save webmention: %w", err)
+	}
+
+	return m, nil
+}
+
+// linksTo reads an HTML page and reports whether it links to target, along
+// with the page's title.
+func linksTo(r io.Reader, base *url.URL, target string) (string, bool, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", false, err
+	}
+
+	var title string
+	found := false
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.Data == "title" && title == "" && n.FirstChild != nil {
+				title = strings.TrimSpace(n.FirstChild.Data)
+			}
+
+			for _, a := range n.Attr {
+				if a.Key != "href" && a.Key != "src" {
+					continue
+				}
+
+				if u, err := base.Parse(strings.TrimSpace(a.Val)); err == nil && u.String() == target {
+					found = true
+				}
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	return title, found, nil
+}
+
+// DiscoverWebmentionEndpoint finds where to send webmentions for a page. It
+// checks the HTTP Link header first, then <link> and <a> elements. It returns
+// an empty string if the page has no endpoint.
+func DiscoverWebmentionEndpoint(ctx context.Context, f Fetcher, target string) (string, error) {
+	tgt, err := parseWebURL(target)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tgt.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("fetching %s returned %s", tgt, resp.Status)
+	}
+
+	// Redirects change what relative endpoints are relative to.
+	base := tgt
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+
+	for _, h := range resp.Header.Values("Link") {
+		if href, ok := webmentionFromLinkHeader(h); ok {
+			return resolveEndpoint(base, href)
+		}
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return "", nil
+	}
+
+	doc, err := html.Parse(io.LimitReader(resp.Body, maxWebmentionBody))
+	if err != nil {
+		return "", err
+	}
+
+	href, ok := webmentionFromHTML(doc)
+	if !ok {
+		return "", nil
+	}
+
+	return resolveEndpoint(base, href)
+}
+
+func resolveEndpoint(base *url.URL, href string) (string, error) {
+	u, err := base.Parse(href)
+	if err != nil {
+		return "", err
+	}
+
+	return u.String(), nil
+}
+
+// webmentionFromLinkHeader finds a webmention endpoint in a Link header, such
+// as `<https://example.com/wm>; rel="webmention"`.
+func webmentionFromLinkHeader(header string) (string, bool) {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		href := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(href, "<") || !strings.HasSuffix(href, ">") {
+			continue
+		}
+
+		for _, param := range parts[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "rel") {
+				continue
+			}
+
+			if hasRel(strings.Trim(strings.TrimSpace(kv[1]), `"`), "webmention") {
+				return strings.Trim(href, "<>"), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// webmentionFromHTML finds the first <link> or <a> with rel="webmention".
+func webmentionFromHTML(n *html.Node) (string, bool) {
+	if n.Type == html.ElementNode && (n.Data == "link" || n.Data == "a") {
+		var href, rel string
+		hasHref := false
+		for _, a := range n.Attr {
+			switch a.Key {
+			case "href":
+				href = a.Val
+				hasHref = true
+			case "rel":
+				rel = a.Val
+			}
+		}
+
+		if hasHref && hasRel(rel, "webmention") {
+			return href, true
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if href, ok := webmentionFromHTML(c); ok {
+			return href, true
+		}
+	}
+
+	return "", false
+}
+
+func hasRel(rels, want string) bool {
+	for _, r := range strings.Fields(rels) {
+		if strings.EqualFold(r, want) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SendWebmention tells target that source links to it, if target has a
+// webmention endpoint. It returns false if there was no endpoint.
+func SendWebmention(ctx context.Context, f Fetcher, source, target string) (bool, error) {
+	endpoint, err := DiscoverWebmentionEndpoint(ctx, f, target)
+	if err != nil {
+		return false, err
+	}
+
+	if endpoint == "" {
+		return false, nil
+	}
+
+	form := url.Values{"source": {source}, "target": {target}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, fmt.Errorf("webmention to %s returned %s", endpoint, resp.Status)
+	}
+
+	return true, nil
+}
+
+// SendPostWebmentions sends a webmention to every link in a post. Failures
+// are logged, so one broken site does not stop the rest. It returns the
+// number of webmentions sent.
+func SendPostWebmentions(ctx context.Context, f Fetcher, p *Post) (int, error) {
+	links, err := p.Links(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, l := range links {
+		ok, err := SendWebmention(ctx, f, p.URI().String(), l.URI.String())
+		if err != nil {
+			log.Warnw("could not send webmention", "post", p.ID, "target", l.URI.String(), "error", err)
+			continue
+		}
+
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
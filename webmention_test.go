@@ -0,0 +1,130 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverWebmentionEndpoint(t *testing.T) {
+	tests := map[string]struct {
+		header string
+		body   string
+		want   string
+	}{
+		"link header":   {header: `</wm>; rel="webmention"`, body: `<a rel="webmention" href="/other">`, want: "/wm"},
+		"link element":  {body: `<html><head><link rel="webmention" href="/endpoint"></head></html>`, want: "/endpoint"},
+		"anchor":        {body: `<p><a href="https://wm.example.com/x" rel="nofollow webmention">wm</a></p>`, want: "https://wm.example.com/x"},
+		"empty href":    {body: `<link rel="webmention" href="">`, want: "/page"},
+		"no endpoint":   {body: `<a href="/foo">foo</a>`, want: ""},
+		"multiple rels": {header: `</a>; rel="me", </b>; rel="webmention"`, want: "/b"},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.header != "" {
+					w.Header().Set("Link", tc.header)
+				}
+				w.Header().Set("Content-Type", "text/html")
+				fmt.Fprint(w, tc.body)
+			}))
+			defer srv.Close()
+
+			got, err := DiscoverWebmentionEndpoint(context.Background(), srv.Client(), srv.URL+"/page")
+			if err != nil {
+				t.Fatalf("DiscoverWebmentionEndpoint: %v", err)
+			}
+
+			want := tc.want
+			if strings.HasPrefix(want, "/") {
+				want = srv.URL + want
+			}
+
+			if got != want {
+				t.Errorf("DiscoverWebmentionEndpoint() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSendWebmention(t *testing.T) {
+	var got url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `</webmention>; rel="webmention"`)
+	})
+	mux.HandleFunc("/webmention", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		got = r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ok, err := SendWebmention(context.Background(), srv.Client(), "https://writing.natwelch.com/post/hi", srv.URL+"/post")
+	if err != nil {
+		t.Fatalf("SendWebmention: %v", err)
+	}
+
+	if !ok {
+		t.Fatal("SendWebmention() = false, want true")
+	}
+
+	if got.Get("source") != "https://writing.natwelch.com/post/hi" || got.Get("target") != srv.URL+"/post" {
+		t.Errorf("endpoint got %v", got)
+	}
+}
+
+func TestLinksTo(t *testing.T) {
+	base, err := url.Parse("https://example.com/blog/entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		body      string
+		target    string
+		wantTitle string
+		wantFound bool
+	}{
+		"absolute": {
+			body:      `<html><head><title> Hi </title></head><body><a href="https://writing.natwelch.com/post/1">x</a></body></html>`,
+			target:    "https://writing.natwelch.com/post/1",
+			wantTitle: "Hi",
+			wantFound: true,
+		},
+		"relative": {
+			body:      `<a href="../other">x</a>`,
+			target:    "https://example.com/other",
+			wantFound: true,
+		},
+		"missing": {
+			body:   `<a href="https://writing.natwelch.com/post/2">x</a>`,
+			target: "https://writing.natwelch.com/post/1",
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc // capture range variable
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			title, found, err := linksTo(strings.NewReader(tc.body), base, tc.target)
+			if err != nil {
+				t.Fatalf("linksTo: %v", err)
+			}
+
+			if title != tc.wantTitle || found != tc.wantFound {
+				t.Errorf("linksTo() = %q, %v, want %q, %v", title, found, tc.wantTitle, tc.wantFound)
+			}
+		})
+	}
+}